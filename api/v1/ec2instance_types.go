@@ -24,10 +24,20 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // Ec2instanceSpec defines the desired state of Ec2instance
+// +kubebuilder:validation:XValidation:rule="has(self.amiId) || has(self.instanceID)",message="amiId is required unless an existing instanceID is adopted"
+// +kubebuilder:validation:XValidation:rule="has(self.region) || has(self.providerConfigRef)",message="region is required unless it comes from a providerConfigRef"
+// +kubebuilder:validation:XValidation:rule="has(self.region) == has(oldSelf.region) && (!has(self.region) || self.region == oldSelf.region)",message="region cannot be changed"
+// +kubebuilder:validation:XValidation:rule="has(self.providerConfigRef) == has(oldSelf.providerConfigRef) && (!has(self.providerConfigRef) || self.providerConfigRef == oldSelf.providerConfigRef)",message="providerConfigRef cannot be changed"
 type Ec2instanceSpec struct {
-	InstanceType      string            `json:"instanceType"`
-	AMIId             string            `json:"amiId"`
-	Region            string            `json:"region"`
+	InstanceType string `json:"instanceType"`
+	AMIId        string `json:"amiId,omitempty"`
+	// Region may be left empty to use the region of the ProviderConfig. The region in use is
+	// recorded in status.region, so the instance stays there if the ProviderConfig changes.
+	// It cannot be changed after creation.
+	Region string `json:"region,omitempty"`
+	// ProviderConfigRef names the ProviderConfig describing the AWS account to use.
+	// Empty uses the operator's own credentials. It cannot be changed after creation.
+	ProviderConfigRef string            `json:"providerConfigRef,omitempty"`
 	AvailabilityZone  string            `json:"availabilityZone,omitempty"`
 	KeyPair           string            `json:"keyPair,omitempty"`
 	SecurityGroups    []string          `json:"securityGroups,omitempty"`
@@ -36,14 +46,37 @@ type Ec2instanceSpec struct {
 	Tags              map[string]string `json:"tags,omitempty"`
 	Storage           StorageConfig     `json:"storage,omitempty"`
 	AssociatePublicIP bool              `json:"associatePublicIP,omitempty"`
+	// PowerState is the desired power state of the instance: running (default), stopped
+	// or hibernated. Hibernating requires the instance to be launched with Hibernation set.
+	// +kubebuilder:validation:Enum=running;stopped;hibernated
+	PowerState string `json:"powerState,omitempty"`
+	// Hibernation enables hibernation support when the instance is launched. AWS requires
+	// an encrypted root volume large enough to hold the instance memory.
+	Hibernation bool `json:"hibernation,omitempty"`
+	// DeletionPolicy decides what happens to the instance when the resource is deleted:
+	// Delete (default) terminates it, Stop stops it and Retain leaves it running.
+	// +kubebuilder:validation:Enum=Delete;Retain;Stop
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// InstanceID adopts an existing instance instead of launching a new one. The instance
+	// is tagged as owned by this resource and managed by the operator from then on.
+	InstanceID string `json:"instanceID,omitempty"`
 }
 
+// Desired power states for Ec2instanceSpec.PowerState
+const (
+	PowerStateRunning    = "running"
+	PowerStateStopped    = "stopped"
+	PowerStateHibernated = "hibernated"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="InstanceType",type="string",JSONPath=".spec.instanceType",description="The EC2 instance type"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="The lifecycle phase of the EC2 instance"
 // +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="The current state of the EC2 instance"
 // +kubebuilder:printcolumn:name="PublicIP",type="string",JSONPath=".status.publicIP",description="The public IP of the EC2 instance"
 // +kubebuilder:printcolumn:name="InstanceID",type="string",JSONPath=".status.instanceID",description="The AWS instance ID"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether the instance is ready"
 // Ec2Instance is the Schema for the ec2instances API.
 type Ec2instance struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -53,36 +86,116 @@ type Ec2instance struct {
 	Status Ec2instanceStatus `json:"status,omitempty,omitzero"`
 }
 
+// InstancePhase is the step of the operator's lifecycle state machine an Ec2instance is in.
+// The AWS instance state itself is reported separately in status.state.
+type InstancePhase string
+
+const (
+	// InstancePhaseLaunching means RunInstances was called and the instance is not running yet.
+	InstancePhaseLaunching InstancePhase = "Launching"
+	// InstancePhaseAvailable means the instance reached the state the spec asks for.
+	InstancePhaseAvailable InstancePhase = "Available"
+	// InstancePhaseResizing means the instance is being stopped, modified and started again
+	// to apply a new instance type.
+	InstancePhaseResizing InstancePhase = "Resizing"
+	// InstancePhaseTerminating means TerminateInstances was called and the instance is not gone yet.
+	InstancePhaseTerminating InstancePhase = "Terminating"
+	// InstancePhaseTerminated means the instance no longer exists in AWS.
+	InstancePhaseTerminated InstancePhase = "Terminated"
+	// InstancePhaseInvalid means the spec was rejected and no instance was launched.
+	InstancePhaseInvalid InstancePhase = "Invalid"
+)
+
+// AnnotationAllowResize opts an Ec2instance in to disruptive instance type changes.
+// Changing spec.instanceType stops the instance, so it only happens when this is "true".
+const AnnotationAllowResize = "compute.cloud.com/allow-resize"
+
 // Ec2instanceStatus defines the observed state of Ec2instance.
 type Ec2instanceStatus struct {
-	InstanceID string `json:"instanceID,omitempty"`
-	State      string `json:"state,omitempty"`
-	PublicIP   string `json:"publicIP,omitempty"`
-	PrivateIP  string `json:"privateIP,omitempty"`
-	PublicDNS  string `json:"publicDNS,omitempty"`
-	PrivateDNS string `json:"privateDNS,omitempty"`
-	LaunchTime string `json:"launchTime,omitempty"`
+	// Phase is where the instance is in the create/delete lifecycle.
+	Phase      InstancePhase `json:"phase,omitempty"`
+	InstanceID string        `json:"instanceID,omitempty"`
+	// Region is the AWS region the instance lives in, from spec.region or the ProviderConfig.
+	Region string `json:"region,omitempty"`
+	// Adopted is true when the instance was created outside the operator and adopted.
+	Adopted bool `json:"adopted,omitempty"`
+	// InstanceType is the instance type AWS reports for the running instance.
+	InstanceType string `json:"instanceType,omitempty"`
+	State        string `json:"state,omitempty"`
+	PublicIP     string `json:"publicIP,omitempty"`
+	PrivateIP    string `json:"privateIP,omitempty"`
+	PublicDNS    string `json:"publicDNS,omitempty"`
+	PrivateDNS   string `json:"privateDNS,omitempty"`
+	LaunchTime   string `json:"launchTime,omitempty"`
+	// Message explains the current state, e.g. why the spec was rejected.
+	Message string `json:"message,omitempty"`
+	// Volumes lists the EBS volumes attached to the instance, keyed by device name.
+	Volumes []AttachedVolume `json:"volumes,omitempty"`
+	// ObservedGeneration is the spec generation the status was last computed from.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions holds the Ready, Synced and Error conditions.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// AttachedVolume describes an EBS volume attached to the EC2 instance.
+type AttachedVolume struct {
+	DeviceName string `json:"deviceName"`
+	VolumeID   string `json:"volumeID"`
 }
 
 // StorageConfig defines the storage configuration for the EC2 instance.
 type StorageConfig struct {
-	RootVolume        VolumeConfig   `json:"rootVolume,omitempty"`
+	RootVolume VolumeConfig `json:"rootVolume,omitempty"`
+	// +kubebuilder:validation:XValidation:rule="self.all(v, has(v.size))",message="additional volumes need a size"
 	AdditionalVolumes []VolumeConfig `json:"additionalVolumes,omitempty"`
 }
 
 // VolumeConfig defines the configuration for a volume in the EC2 instance.
+// Size is in GiB. For the root volume an unset size keeps the AMI default.
+// Type is the EBS volume type (gp2, gp3, io1, io2, st1, sc1, standard).
+// DeviceName defaults to the AMI root device for the root volume and to
+// /dev/sdf, /dev/sdg, ... for additional volumes.
 type VolumeConfig struct {
-	Size       int32  `json:"size"`
+	// +kubebuilder:validation:Minimum=1
+	Size       int32  `json:"size,omitempty"`
 	Type       string `json:"type,omitempty"`
 	DeviceName string `json:"deviceName,omitempty"`
 	Encrypted  bool   `json:"encrypted,omitempty"`
 }
 
+// DeletionPolicy decides what happens to the AWS resource when its custom resource is deleted.
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the AWS resource. This is the default.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain leaves the AWS resource untouched and only removes the finalizer.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyStop stops the EC2 instance instead of terminating it. Ec2instance only.
+	DeletionPolicyStop DeletionPolicy = "Stop"
+)
+
+// Condition types reported by the operator on both Ec2instance and S3Bucket.
+const (
+	// ConditionReady is True when the AWS resource exists and is usable.
+	ConditionReady = "Ready"
+	// ConditionSynced is True when the last reconcile brought AWS in line with the spec.
+	ConditionSynced = "Synced"
+	// ConditionError is True when the resource hit an error that retrying will not fix.
+	// Failures a retry may fix only set Synced to False and leave Error False.
+	ConditionError = "Error"
+)
+
+// Condition describes one aspect of the observed state of a resource.
+// Status is one of "True", "False" or "Unknown".
 type Condition struct {
 	Type               string      `json:"type"`
 	Status             string      `json:"status"`
 	Reason             string      `json:"reason,omitempty"`
 	Message            string      `json:"message,omitempty"`
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
 	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
 }
 
@@ -96,12 +209,15 @@ type Ec2instanceList struct {
 }
 
 type CreatedInstanceInfo struct {
-	InstanceId string `json:"instanceId"`
-	State      string `json:"state"`
-	PrivateIP  string `json:"privateIP"`
-	PublicIP   string `json:"publicIP"`
-	PrivateDNS string `json:"privateDNS"`
-	PublicDNS  string `json:"publicDNS"`
+	InstanceId   string           `json:"instanceId"`
+	InstanceType string           `json:"instanceType,omitempty"`
+	State        string           `json:"state"`
+	PrivateIP    string           `json:"privateIP"`
+	PublicIP     string           `json:"publicIP"`
+	PrivateDNS   string           `json:"privateDNS"`
+	PublicDNS    string           `json:"publicDNS"`
+	LaunchTime   string           `json:"launchTime,omitempty"`
+	Volumes      []AttachedVolume `json:"volumes,omitempty"`
 }
 
 func init() {
@@ -0,0 +1,107 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CredentialsSource selects where a ProviderConfig gets its base AWS credentials
+type CredentialsSource string
+
+const (
+	// CredentialsSourceDefault uses the operator's own credentials: the default credential
+	// chain (IRSA, EKS Pod Identity, instance profiles, environment) or --aws-credentials-secret
+	CredentialsSourceDefault CredentialsSource = "Default"
+	// CredentialsSourceSecret reads static credentials from SecretRef
+	CredentialsSourceSecret CredentialsSource = "Secret"
+)
+
+// ProviderConfigSpec describes an AWS account the operator can manage resources in
+type ProviderConfigSpec struct {
+	// Credentials are the base credentials for the account
+	// +kubebuilder:default={}
+	Credentials ProviderCredentials `json:"credentials,omitempty"`
+	// AssumeRole is assumed with the base credentials, typically a role in the target account
+	AssumeRole *AssumeRoleConfig `json:"assumeRole,omitempty"`
+	// Region is used by resources that leave spec.region empty
+	Region string `json:"region,omitempty"`
+	// Tags are added to every AWS resource managed through this ProviderConfig.
+	// Tags set on the resource itself take precedence.
+	Tags map[string]string `json:"tags,omitempty"`
+	// AllowedNamespaces lists the namespaces whose resources may use this ProviderConfig.
+	// Empty allows every namespace.
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+}
+
+// ProviderCredentials selects the base credentials of a ProviderConfig
+// +kubebuilder:validation:XValidation:rule="self.source != 'Secret' || has(self.secretRef)",message="secretRef is required when source is Secret"
+type ProviderCredentials struct {
+	// +kubebuilder:validation:Enum=Default;Secret
+	// +kubebuilder:default=Default
+	Source CredentialsSource `json:"source,omitempty"`
+	// SecretRef names a Secret holding AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and optionally
+	// AWS_SESSION_TOKEN. The operator may only read Secrets in its own namespace.
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+}
+
+// SecretReference points at a Secret in a given namespace
+type SecretReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// AssumeRoleConfig describes an IAM role to assume
+type AssumeRoleConfig struct {
+	RoleARN string `json:"roleARN"`
+	// ExternalID is passed to AssumeRole when the role's trust policy requires one
+	ExternalID string `json:"externalID,omitempty"`
+	// SessionName names the AssumeRole session. Defaults to ec2instance-operator.
+	SessionName string `json:"sessionName,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Region",type="string",JSONPath=".spec.region",description="The default AWS region"
+// +kubebuilder:printcolumn:name="Role",type="string",JSONPath=".spec.assumeRole.roleARN",description="The IAM role assumed"
+
+// ProviderConfig is the Schema for the providerconfigs API. Ec2instance and S3Bucket
+// resources reference it by name in spec.providerConfigRef.
+type ProviderConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty,omitzero"`
+
+	// spec defines the AWS account
+	// +required
+	Spec ProviderConfigSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// ProviderConfigList contains a list of ProviderConfig
+type ProviderConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProviderConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProviderConfig{}, &ProviderConfigList{})
+}
@@ -24,32 +24,290 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // S3BucketSpec defines the desired state of S3Bucket
+// +kubebuilder:validation:XValidation:rule="has(self.region) || has(self.providerConfigRef)",message="region is required unless it comes from a providerConfigRef"
+// +kubebuilder:validation:XValidation:rule="has(self.region) == has(oldSelf.region) && (!has(self.region) || self.region == oldSelf.region)",message="region cannot be changed"
+// +kubebuilder:validation:XValidation:rule="has(self.providerConfigRef) == has(oldSelf.providerConfigRef) && (!has(self.providerConfigRef) || self.providerConfigRef == oldSelf.providerConfigRef)",message="providerConfigRef cannot be changed"
 type S3BucketSpec struct {
 	BucketName string `json:"bucketName"`
-	Region     string `json:"region"`
-	ACL        string `json:"acl,omitempty"`
+	// Region may be left empty to use the region of the ProviderConfig. The region in use is
+	// recorded in status.region, so the bucket stays there if the ProviderConfig changes.
+	// It cannot be changed after creation.
+	Region string `json:"region,omitempty"`
+	// ProviderConfigRef names the ProviderConfig describing the AWS account to use.
+	// Empty uses the operator's own credentials. It cannot be changed after creation.
+	ProviderConfigRef string `json:"providerConfigRef,omitempty"`
+	// ACL is a canned ACL applied after creation. Anything other than private needs
+	// ObjectOwnership BucketOwnerPreferred or ObjectWriter, and public ACLs also need
+	// PublicAccessBlock.BlockPublicAcls set to false.
+	// +kubebuilder:validation:Enum=private;public-read;public-read-write;authenticated-read
+	ACL string `json:"acl,omitempty"`
+	// ObjectOwnership controls whether ACLs are used. BucketOwnerEnforced (default) disables them.
+	// +kubebuilder:validation:Enum=BucketOwnerEnforced;BucketOwnerPreferred;ObjectWriter
+	// +kubebuilder:default=BucketOwnerEnforced
+	ObjectOwnership string `json:"objectOwnership,omitempty"`
+	// PublicAccessBlock blocks all public access unless individual flags are set to false
+	// +kubebuilder:default={}
+	PublicAccessBlock PublicAccessBlockConfig `json:"publicAccessBlock,omitempty"`
 	// Versioning indicates whether versioning is enabled for the bucket.
 	// Possible values are "Enabled" or "Suspended".
+	// +kubebuilder:validation:Enum=Enabled;Suspended
 	Versioning string `json:"versioning,omitempty"`
 	// StorageClass defines the default storage class for objects in the bucket.
-	// Examples include "STANDARD", "REDUCED_REDUNDANCY", "GLACIER", etc.
+	// Examples include "STANDARD", "STANDARD_IA", "GLACIER", etc.
+	// S3 has no bucket-wide default storage class, so anything other than STANDARD is
+	// applied as a lifecycle rule that transitions every object to this class. Classes
+	// S3 cannot transition objects to, such as REDUCED_REDUNDANCY, are rejected.
 	StorageClass string `json:"storageClass,omitempty"`
+	// LifecycleRules are applied with PutBucketLifecycleConfiguration
+	LifecycleRules []LifecycleRule `json:"lifecycleRules,omitempty"`
+	// Website turns on static website hosting. Serving it publicly also needs the
+	// publicAccessBlock flags for policies turned off and a policy allowing s3:GetObject.
+	Website *WebsiteConfig `json:"website,omitempty"`
+	// CORSRules are applied with PutBucketCors and removed with DeleteBucketCors when emptied
+	CORSRules []CORSRule `json:"corsRules,omitempty"`
+	// Tags are applied to the bucket together with its labels and the Name, ManagedBy,
+	// Namespace and OwnerUID tags set by the operator
+	Tags map[string]string `json:"tags,omitempty"`
+	// Policy is applied with PutBucketPolicy and deleted when removed from the spec
+	Policy *BucketPolicy `json:"policy,omitempty"`
+	// Encryption sets the default server-side encryption of the bucket.
+	// When unset, the bucket keeps the S3 default (SSE-S3).
+	Encryption *BucketEncryption `json:"encryption,omitempty"`
+	// DeletionPolicy decides what happens to the bucket when the resource is deleted.
+	// Delete (default) deletes the bucket, Retain leaves it and its objects in place.
+	// +kubebuilder:validation:Enum=Delete;Retain
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// ForceDelete deletes every object, object version and delete marker before deleting
+	// the bucket. Without it, deleting a bucket that still has objects is reported with a
+	// BucketNotEmpty condition and retried until the bucket is emptied.
+	ForceDelete bool `json:"forceDelete,omitempty"`
+	// Adopt takes over an existing bucket named BucketName instead of creating it.
+	// Without it, a bucket that already exists in the account is reported as an error.
+	Adopt bool `json:"adopt,omitempty"`
+}
+
+// LifecycleRule describes one S3 lifecycle rule
+type LifecycleRule struct {
+	// ID names the rule. Defaults to rule-<index>.
+	ID string `json:"id,omitempty"`
+	// Prefix limits the rule to object keys starting with it. Empty applies to the whole bucket.
+	Prefix string `json:"prefix,omitempty"`
+	// Transitions move objects to another storage class a number of days after creation
+	Transitions []LifecycleTransition `json:"transitions,omitempty"`
+	// ExpirationDays deletes objects this many days after creation
+	// +kubebuilder:validation:Minimum=1
+	ExpirationDays int32 `json:"expirationDays,omitempty"`
+	// NoncurrentVersionExpirationDays deletes old object versions this many days after they
+	// stop being the current version
+	// +kubebuilder:validation:Minimum=1
+	NoncurrentVersionExpirationDays int32 `json:"noncurrentVersionExpirationDays,omitempty"`
+	// AbortIncompleteMultipartUploadDays cleans up multipart uploads that were not completed
+	// within this many days
+	// +kubebuilder:validation:Minimum=1
+	AbortIncompleteMultipartUploadDays int32 `json:"abortIncompleteMultipartUploadDays,omitempty"`
+}
+
+// LifecycleTransition moves objects to StorageClass after Days
+type LifecycleTransition struct {
+	// +kubebuilder:validation:Minimum=0
+	Days int32 `json:"days"`
+	// +kubebuilder:validation:Enum=GLACIER;STANDARD_IA;ONEZONE_IA;INTELLIGENT_TIERING;DEEP_ARCHIVE;GLACIER_IR
+	StorageClass string `json:"storageClass"`
+}
+
+// WebsiteConfig describes static website hosting for a bucket. Either serve the bucket with
+// IndexDocument, or send every request elsewhere with RedirectAllRequestsTo.
+// +kubebuilder:validation:XValidation:rule="has(self.indexDocument) != has(self.redirectAllRequestsTo)",message="exactly one of indexDocument or redirectAllRequestsTo must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.redirectAllRequestsTo) || (!has(self.errorDocument) && !has(self.routingRules))",message="redirectAllRequestsTo cannot be combined with errorDocument or routingRules"
+type WebsiteConfig struct {
+	// IndexDocument is served for requests to a directory, e.g. index.html
+	IndexDocument string `json:"indexDocument,omitempty"`
+	// ErrorDocument is served when a request fails with a 4XX error
+	ErrorDocument string `json:"errorDocument,omitempty"`
+	// RedirectAllRequestsTo redirects every request to another host
+	RedirectAllRequestsTo *WebsiteRedirect `json:"redirectAllRequestsTo,omitempty"`
+	// RoutingRules redirect requests that match a condition
+	RoutingRules []RoutingRule `json:"routingRules,omitempty"`
+}
+
+// WebsiteRedirect sends requests to another host
+type WebsiteRedirect struct {
+	HostName string `json:"hostName"`
+	// Protocol defaults to the protocol of the original request
+	// +kubebuilder:validation:Enum=http;https
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// RoutingRule redirects requests matching Condition
+type RoutingRule struct {
+	// Condition limits the rule to some requests. Without it, the rule applies to all of them.
+	Condition *RoutingRuleCondition `json:"condition,omitempty"`
+	Redirect  RoutingRuleRedirect   `json:"redirect"`
+}
+
+// RoutingRuleCondition matches requests by key prefix or by the error they would return
+type RoutingRuleCondition struct {
+	KeyPrefixEquals string `json:"keyPrefixEquals,omitempty"`
+	// HTTPErrorCodeReturnedEquals matches requests that fail with this code, e.g. "404"
+	HTTPErrorCodeReturnedEquals string `json:"httpErrorCodeReturnedEquals,omitempty"`
+}
+
+// RoutingRuleRedirect describes where a matching request is sent
+// +kubebuilder:validation:XValidation:rule="!has(self.replaceKeyPrefixWith) || !has(self.replaceKeyWith)",message="replaceKeyPrefixWith and replaceKeyWith are mutually exclusive"
+type RoutingRuleRedirect struct {
+	HostName string `json:"hostName,omitempty"`
+	// +kubebuilder:validation:Enum=http;https
+	Protocol             string `json:"protocol,omitempty"`
+	ReplaceKeyPrefixWith string `json:"replaceKeyPrefixWith,omitempty"`
+	ReplaceKeyWith       string `json:"replaceKeyWith,omitempty"`
+	// HTTPRedirectCode defaults to 301
+	HTTPRedirectCode string `json:"httpRedirectCode,omitempty"`
+}
+
+// CORSRule allows cross-origin requests from browsers
+type CORSRule struct {
+	// ID names the rule
+	ID string `json:"id,omitempty"`
+	// AllowedOrigins such as https://app.example.com, or "*"
+	// +kubebuilder:validation:MinItems=1
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:items:Enum=GET;PUT;POST;DELETE;HEAD
+	AllowedMethods []string `json:"allowedMethods"`
+	// AllowedHeaders are the request headers browsers may send in a preflight request
+	AllowedHeaders []string `json:"allowedHeaders,omitempty"`
+	// ExposeHeaders are the response headers browsers may read
+	ExposeHeaders []string `json:"exposeHeaders,omitempty"`
+	// MaxAgeSeconds is how long browsers may cache the preflight response
+	// +kubebuilder:validation:Minimum=0
+	MaxAgeSeconds int32 `json:"maxAgeSeconds,omitempty"`
+}
+
+// PublicAccessBlockConfig holds the four S3 Block Public Access flags.
+// Each one defaults to true.
+type PublicAccessBlockConfig struct {
+	// +kubebuilder:default=true
+	BlockPublicAcls *bool `json:"blockPublicAcls,omitempty"`
+	// +kubebuilder:default=true
+	IgnorePublicAcls *bool `json:"ignorePublicAcls,omitempty"`
+	// +kubebuilder:default=true
+	BlockPublicPolicy *bool `json:"blockPublicPolicy,omitempty"`
+	// +kubebuilder:default=true
+	RestrictPublicBuckets *bool `json:"restrictPublicBuckets,omitempty"`
+}
+
+// PublicAccessBlockStatus is the Block Public Access configuration AWS reports for a bucket
+type PublicAccessBlockStatus struct {
+	BlockPublicAcls       bool `json:"blockPublicAcls"`
+	IgnorePublicAcls      bool `json:"ignorePublicAcls"`
+	BlockPublicPolicy     bool `json:"blockPublicPolicy"`
+	RestrictPublicBuckets bool `json:"restrictPublicBuckets"`
+}
+
+// BucketPolicy is either a raw policy document or a list of statements the operator turns
+// into one
+// +kubebuilder:validation:XValidation:rule="has(self.json) != has(self.statements)",message="exactly one of json or statements must be set"
+type BucketPolicy struct {
+	// JSON is a complete bucket policy document
+	JSON string `json:"json,omitempty"`
+	// Statements are rendered into a policy document scoped to this bucket
+	// +kubebuilder:validation:MinItems=1
+	Statements []PolicyStatement `json:"statements,omitempty"`
+}
+
+// PolicyStatement is one statement of a bucket policy
+type PolicyStatement struct {
+	// Sid identifies the statement
+	Sid string `json:"sid,omitempty"`
+	// +kubebuilder:validation:Enum=Allow;Deny
+	// +kubebuilder:default=Allow
+	Effect string `json:"effect,omitempty"`
+	// Principals are AWS account, user or role ARNs. "*" means everyone.
+	// +kubebuilder:validation:MinItems=1
+	Principals []string `json:"principals"`
+	// Actions such as s3:GetObject
+	// +kubebuilder:validation:MinItems=1
+	Actions []string `json:"actions"`
+	// ResourcePrefixes limit the statement to objects under these key prefixes.
+	// Empty covers the bucket and every object in it.
+	ResourcePrefixes []string `json:"resourcePrefixes,omitempty"`
+	// Conditions map a condition operator to condition keys and their values,
+	// e.g. {"StringEquals": {"aws:PrincipalOrgID": ["o-123"]}}
+	Conditions map[string]map[string][]string `json:"conditions,omitempty"`
+}
+
+// BucketEncryption describes the default server-side encryption of a bucket
+// +kubebuilder:validation:XValidation:rule="!has(self.kmsKeyID) || self.algorithm == 'aws:kms'",message="kmsKeyID requires algorithm aws:kms"
+type BucketEncryption struct {
+	// Algorithm is AES256 (SSE-S3) or aws:kms (SSE-KMS)
+	// +kubebuilder:validation:Enum=AES256;"aws:kms"
+	// +kubebuilder:default=AES256
+	Algorithm string `json:"algorithm,omitempty"`
+	// KMSKeyID is the ID, alias or ARN of the KMS key. Empty uses the AWS managed aws/s3 key.
+	KMSKeyID string `json:"kmsKeyID,omitempty"`
+	// BucketKeyEnabled uses an S3 Bucket Key to reduce the number of KMS requests
+	BucketKeyEnabled bool `json:"bucketKeyEnabled,omitempty"`
+}
+
+// BucketEncryptionStatus is the default encryption AWS reports for a bucket
+type BucketEncryptionStatus struct {
+	Algorithm        string `json:"algorithm,omitempty"`
+	KMSKeyID         string `json:"kmsKeyID,omitempty"`
+	BucketKeyEnabled bool   `json:"bucketKeyEnabled,omitempty"`
 }
 
 // S3BucketStatus defines the observed state of S3Bucket.
 type S3BucketStatus struct {
 	// BucketARN is the Amazon Resource Name of the S3 bucket
 	BucketARN string `json:"bucketARN,omitempty"`
+	// Region is the AWS region the bucket lives in, from spec.region or the ProviderConfig
+	Region string `json:"region,omitempty"`
 	// Location is the AWS region where the bucket was created
 	Location string `json:"location,omitempty"`
 	// Created indicates whether the bucket has been successfully created
 	Created bool `json:"created,omitempty"`
+	// Adopted indicates the bucket existed before and was adopted instead of created
+	Adopted bool `json:"adopted,omitempty"`
+	// Versioning is the versioning status AWS reports: Enabled, Suspended or Disabled
+	Versioning string `json:"versioning,omitempty"`
+	// Encryption is the default encryption AWS reports for the bucket
+	Encryption *BucketEncryptionStatus `json:"encryption,omitempty"`
+	// ObjectOwnership is the object ownership setting of the bucket
+	ObjectOwnership string `json:"objectOwnership,omitempty"`
+	// PublicAccessBlock is the Block Public Access configuration of the bucket
+	PublicAccessBlock *PublicAccessBlockStatus `json:"publicAccessBlock,omitempty"`
+	// Tags are the tags the operator manages on the bucket. Other tags on the bucket are left alone.
+	Tags map[string]string `json:"tags,omitempty"`
+	// PolicyApplied is true while the operator manages a bucket policy
+	PolicyApplied bool `json:"policyApplied,omitempty"`
+	// WebsiteEndpoint is the URL the bucket is served from when website hosting is on
+	WebsiteEndpoint string `json:"websiteEndpoint,omitempty"`
+	// CORSRules is the number of CORS rules applied to the bucket
+	CORSRules int32 `json:"corsRules,omitempty"`
+	// LifecycleRules is the number of lifecycle rules applied to the bucket
+	LifecycleRules int32 `json:"lifecycleRules,omitempty"`
+	// ObjectsDeleted counts the object versions and delete markers removed while emptying
+	// the bucket for deletion
+	ObjectsDeleted int64 `json:"objectsDeleted,omitempty"`
+	// Drift lists the fields that differed from the spec on the last sync. They are corrected
+	// in the same sync.
+	Drift []string `json:"drift,omitempty"`
 	// LastSyncTime is the last time the bucket status was synchronized with AWS
 	LastSyncTime string `json:"lastSyncTime,omitempty"`
+	// ObservedGeneration is the spec generation the status was last computed from
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions holds the Ready, Synced and Error conditions
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="BucketName",type="string",JSONPath=".spec.bucketName",description="The S3 bucket name"
+// +kubebuilder:printcolumn:name="Region",type="string",JSONPath=".status.region",description="The AWS region of the bucket"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether the bucket is ready"
+// +kubebuilder:printcolumn:name="Website",type="string",JSONPath=".status.websiteEndpoint",priority=1,description="The static website endpoint"
 
 // S3Bucket is the Schema for the s3buckets API
 type S3Bucket struct {
@@ -84,6 +342,21 @@ type CreatedBucketInfo struct {
 	Region     string `json:"region"`
 }
 
+// BucketConfigurationInfo is what AWS reports after the bucket settings were applied
+type BucketConfigurationInfo struct {
+	Versioning     string `json:"versioning"`
+	LifecycleRules int32  `json:"lifecycleRules"`
+	CORSRules      int32  `json:"corsRules"`
+	// WebsiteEndpoint is empty when website hosting is off
+	WebsiteEndpoint string `json:"websiteEndpoint,omitempty"`
+	// Encryption is nil when the bucket reports no default encryption
+	Encryption        *BucketEncryptionStatus  `json:"encryption,omitempty"`
+	ObjectOwnership   string                   `json:"objectOwnership"`
+	PublicAccessBlock *PublicAccessBlockStatus `json:"publicAccessBlock,omitempty"`
+	PolicyApplied     bool                     `json:"policyApplied"`
+	Tags              map[string]string        `json:"tags,omitempty"`
+}
+
 func init() {
 	SchemeBuilder.Register(&S3Bucket{}, &S3BucketList{})
 }
@@ -20,12 +20,14 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -61,6 +63,8 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var awsCredentialsSecret string
+	var awsCredentials controller.AWSCredentialsOptions
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -79,6 +83,14 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&awsCredentialsSecret, "aws-credentials-secret", "",
+		"Read AWS credentials from this Secret, given as <namespace>/<name>, instead of the default credential chain. "+
+			"The Secret holds AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and optionally AWS_SESSION_TOKEN.")
+	flag.StringVar(&awsCredentials.RoleARN, "aws-assume-role-arn", "",
+		"If set, this IAM role is assumed for all AWS calls using the base credentials.")
+	flag.StringVar(&awsCredentials.ExternalID, "aws-external-id", "", "The external ID passed when assuming --aws-assume-role-arn.")
+	flag.StringVar(&awsCredentials.RoleSessionName, "aws-role-session-name", "",
+		"The session name used when assuming --aws-assume-role-arn.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -178,16 +190,30 @@ func main() {
 		os.Exit(1)
 	}
 
+	if awsCredentialsSecret != "" {
+		namespace, name, found := strings.Cut(awsCredentialsSecret, "/")
+		if !found || namespace == "" || name == "" {
+			setupLog.Error(nil, "--aws-credentials-secret must be given as <namespace>/<name>", "value", awsCredentialsSecret)
+			os.Exit(1)
+		}
+		awsCredentials.SecretRef = &types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	// Secrets are read directly from the API server, so the manager never caches them.
+	// Both controllers share the factory and with it the cached AWS clients.
+	awsClients := controller.NewAWSClientFactory(awsCredentials, mgr.GetAPIReader())
+
 	if err := (&controller.Ec2instanceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:     mgr.GetClient(),
+		Scheme:     mgr.GetScheme(),
+		AWSClients: awsClients,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Ec2instance")
 		os.Exit(1)
 	}
 	if err := (&controller.S3BucketReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:     mgr.GetClient(),
+		Scheme:     mgr.GetScheme(),
+		AWSClients: awsClients,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "S3Bucket")
 		os.Exit(1)
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// adoptEc2Instance takes over an instance that was created outside the operator, identified
+// by spec.instanceID. It checks that the instance exists and is not managed by another
+// resource, then tags it and its volumes with the ownership tags.
+func adoptEc2Instance(ctx context.Context, clients AWSClientProvider, ec2Instance *computev1.Ec2instance) (*computev1.CreatedInstanceInfo, error) {
+	l := logf.FromContext(ctx)
+
+	l.Info("=== ADOPTING EXISTING EC2 INSTANCE ===",
+		"instanceID", ec2Instance.Spec.InstanceID,
+		"region", ec2Instance.Status.Region)
+
+	ec2Client, err := clients.EC2(ctx, ec2Instance.Status.Region)
+	if err != nil {
+		l.Error(err, "Failed to create EC2 client")
+		return nil, fmt.Errorf("failed to create EC2 client: %w", err)
+	}
+
+	describeResult, err := ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{ec2Instance.Spec.InstanceID},
+	})
+	if err != nil {
+		if awsErrorCode(err) == "InvalidInstanceID.NotFound" || awsErrorCode(err) == "InvalidInstanceID.Malformed" {
+			return nil, newInvalidSpecError("instance %s not found in region %s", ec2Instance.Spec.InstanceID, ec2Instance.Status.Region)
+		}
+		return nil, fmt.Errorf("failed to describe EC2 instance %s: %w", ec2Instance.Spec.InstanceID, err)
+	}
+	if len(describeResult.Reservations) == 0 || len(describeResult.Reservations[0].Instances) == 0 {
+		return nil, newInvalidSpecError("instance %s not found in region %s", ec2Instance.Spec.InstanceID, ec2Instance.Status.Region)
+	}
+	instance := describeResult.Reservations[0].Instances[0]
+
+	if instance.State != nil && (instance.State.Name == ec2types.InstanceStateNameTerminated ||
+		instance.State.Name == ec2types.InstanceStateNameShuttingDown) {
+		return nil, newInvalidSpecError("instance %s is %s and cannot be adopted", ec2Instance.Spec.InstanceID, instance.State.Name)
+	}
+
+	// Refuse to take an instance away from another custom resource
+	for _, tag := range instance.Tags {
+		if aws.ToString(tag.Key) == ownerUIDTagKey && aws.ToString(tag.Value) != string(ec2Instance.UID) {
+			return nil, newInvalidSpecError("instance %s is already managed by another resource (%s %s)",
+				ec2Instance.Spec.InstanceID, ownerUIDTagKey, aws.ToString(tag.Value))
+		}
+	}
+
+	// Tag the instance and its volumes so they show up as owned by this resource
+	resourceIDs := []string{ec2Instance.Spec.InstanceID}
+	for _, volume := range attachedVolumes(instance.BlockDeviceMappings) {
+		resourceIDs = append(resourceIDs, volume.VolumeID)
+	}
+
+	ownerTags := ownershipTags(ec2Instance)
+	var tags []ec2types.Tag
+	for _, key := range sortedTagKeys(ownerTags) {
+		tags = append(tags, ec2types.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(ownerTags[key]),
+		})
+	}
+
+	if _, err := ec2Client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: resourceIDs,
+		Tags:      tags,
+	}); err != nil {
+		l.Error(err, "Failed to tag adopted instance")
+		return nil, fmt.Errorf("failed to tag EC2 instance %s: %w", ec2Instance.Spec.InstanceID, err)
+	}
+
+	info := instanceInfo(instance)
+	l.Info("=== EC2 INSTANCE ADOPTED ===",
+		"InstanceID", info.InstanceId,
+		"State", info.State)
+
+	return info, nil
+}
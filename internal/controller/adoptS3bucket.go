@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// adoptS3Bucket takes over a bucket that was created outside the operator. It checks that
+// the bucket exists in this account and in the resource's region, then merges the ownership tags into
+// the bucket's existing tags.
+func adoptS3Bucket(ctx context.Context, clients AWSClientProvider, s3Bucket *computev1.S3Bucket) (*computev1.CreatedBucketInfo, error) {
+	l := logf.FromContext(ctx)
+
+	l.Info("=== ADOPTING EXISTING S3 BUCKET ===",
+		"bucketName", s3Bucket.Spec.BucketName,
+		"region", s3Bucket.Status.Region)
+
+	s3Client, err := clients.S3(ctx, s3Bucket.Status.Region)
+	if err != nil {
+		l.Error(err, "Failed to create S3 client")
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	if _, err := s3Client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(s3Bucket.Spec.BucketName),
+	}); err != nil {
+		switch awsErrorCode(err) {
+		case "NotFound", "NoSuchBucket":
+			return nil, newInvalidSpecError("bucket %s does not exist and cannot be adopted", s3Bucket.Spec.BucketName)
+		case "Forbidden":
+			return nil, newInvalidSpecError("bucket %s is not accessible from this account and cannot be adopted", s3Bucket.Spec.BucketName)
+		}
+		return nil, fmt.Errorf("failed to check S3 bucket %s: %w", s3Bucket.Spec.BucketName, err)
+	}
+
+	locationOutput, err := s3Client.GetBucketLocation(ctx, &s3.GetBucketLocationInput{
+		Bucket: aws.String(s3Bucket.Spec.BucketName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get location of S3 bucket %s: %w", s3Bucket.Spec.BucketName, err)
+	}
+	// Buckets in us-east-1 report an empty location constraint
+	bucketRegion := string(locationOutput.LocationConstraint)
+	if bucketRegion == "" {
+		bucketRegion = "us-east-1"
+	}
+	if bucketRegion != s3Bucket.Status.Region {
+		return nil, newInvalidSpecError("bucket %s is in region %s but the resource is in %s",
+			s3Bucket.Spec.BucketName, bucketRegion, s3Bucket.Status.Region)
+	}
+
+	// PutBucketTagging replaces the whole tag set, so merge with what is already there
+	existingTags := map[string]string{}
+	taggingOutput, err := s3Client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{
+		Bucket: aws.String(s3Bucket.Spec.BucketName),
+	})
+	if err != nil && awsErrorCode(err) != "NoSuchTagSet" {
+		return nil, fmt.Errorf("failed to get tags of S3 bucket %s: %w", s3Bucket.Spec.BucketName, err)
+	}
+	if taggingOutput != nil {
+		for _, tag := range taggingOutput.TagSet {
+			existingTags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+
+	if owner, ok := existingTags[ownerUIDTagKey]; ok && owner != string(s3Bucket.UID) {
+		return nil, newInvalidSpecError("bucket %s is already managed by another resource (%s %s)",
+			s3Bucket.Spec.BucketName, ownerUIDTagKey, owner)
+	}
+	for key, value := range ownershipTags(s3Bucket) {
+		existingTags[key] = value
+	}
+
+	tagSet := make([]s3types.Tag, 0, len(existingTags))
+	for _, key := range sortedTagKeys(existingTags) {
+		tagSet = append(tagSet, s3types.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(existingTags[key]),
+		})
+	}
+	if _, err := s3Client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(s3Bucket.Spec.BucketName),
+		Tagging: &s3types.Tagging{TagSet: tagSet},
+	}); err != nil {
+		l.Error(err, "Failed to tag adopted S3 bucket")
+		return nil, fmt.Errorf("failed to tag S3 bucket %s: %w", s3Bucket.Spec.BucketName, err)
+	}
+
+	l.Info("=== S3 BUCKET ADOPTED ===", "bucketName", s3Bucket.Spec.BucketName)
+
+	return &computev1.CreatedBucketInfo{
+		BucketName: s3Bucket.Spec.BucketName,
+		BucketARN:  fmt.Sprintf("arn:aws:s3:::%s", s3Bucket.Spec.BucketName),
+		Location:   bucketRegion,
+		Region:     s3Bucket.Status.Region,
+	}, nil
+}
@@ -3,30 +3,286 @@ package controller
 import (
 	"context"
 	"fmt"
-	"os"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// getAWSConfig returns a general AWS config that can be used for any AWS service
-func getAWSConfig(region string) (aws.Config, error) {
-	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
-	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+// Keys read from a credentials Secret. They match the environment variable names, so the same
+// Secret works for both.
+const (
+	secretAccessKeyIDKey     = "AWS_ACCESS_KEY_ID"
+	secretSecretAccessKeyKey = "AWS_SECRET_ACCESS_KEY"
+	secretSessionTokenKey    = "AWS_SESSION_TOKEN"
+)
+
+// secretCredentialsTTL is how long credentials read from a Secret are used before the Secret
+// is read again, so rotated keys are picked up without a restart
+const secretCredentialsTTL = 5 * time.Minute
+
+// AWSCredentialsOptions selects where the operator gets its AWS credentials. The zero value
+// uses the default credential chain: environment variables, shared config and credentials
+// files, web identity (IRSA), EKS Pod Identity and instance profiles.
+type AWSCredentialsOptions struct {
+	// SecretRef names a Secret holding AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and optionally
+	// AWS_SESSION_TOKEN. It replaces the default chain.
+	SecretRef *types.NamespacedName
+	// RoleARN is assumed with the credentials above
+	RoleARN string
+	// ExternalID is passed to AssumeRole when the role requires one
+	ExternalID string
+	// RoleSessionName names the AssumeRole session. Defaults to the ManagedBy tag value.
+	RoleSessionName string
+}
+
+// EC2API is the part of the EC2 API the operator uses. *ec2.Client implements it, and tests
+// can substitute a fake.
+type EC2API interface {
+	RunInstances(ctx context.Context, params *ec2.RunInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error)
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error)
+	StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error)
+	TerminateInstances(ctx context.Context, params *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error)
+	ModifyInstanceAttribute(ctx context.Context, params *ec2.ModifyInstanceAttributeInput, optFns ...func(*ec2.Options)) (*ec2.ModifyInstanceAttributeOutput, error)
+	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+	DescribeImages(ctx context.Context, params *ec2.DescribeImagesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error)
+	DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
+}
+
+// S3API is the part of the S3 API the operator uses. *s3.Client implements it, and tests can
+// substitute a fake.
+type S3API interface {
+	CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
+	DeleteBucket(ctx context.Context, params *s3.DeleteBucketInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOutput, error)
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error)
+	ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+
+	GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error)
+	PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error)
+	GetBucketOwnershipControls(ctx context.Context, params *s3.GetBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error)
+	PutBucketOwnershipControls(ctx context.Context, params *s3.PutBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.PutBucketOwnershipControlsOutput, error)
+	PutBucketAcl(ctx context.Context, params *s3.PutBucketAclInput, optFns ...func(*s3.Options)) (*s3.PutBucketAclOutput, error)
+	GetBucketTagging(ctx context.Context, params *s3.GetBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error)
+	PutBucketTagging(ctx context.Context, params *s3.PutBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error)
+	GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error)
+	PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error)
+	GetBucketPolicy(ctx context.Context, params *s3.GetBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error)
+	PutBucketPolicy(ctx context.Context, params *s3.PutBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.PutBucketPolicyOutput, error)
+	DeleteBucketPolicy(ctx context.Context, params *s3.DeleteBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketPolicyOutput, error)
+	GetBucketWebsite(ctx context.Context, params *s3.GetBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.GetBucketWebsiteOutput, error)
+	PutBucketWebsite(ctx context.Context, params *s3.PutBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.PutBucketWebsiteOutput, error)
+	DeleteBucketWebsite(ctx context.Context, params *s3.DeleteBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketWebsiteOutput, error)
+	GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error)
+	PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error)
+	DeleteBucketLifecycle(ctx context.Context, params *s3.DeleteBucketLifecycleInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error)
+	GetBucketCors(ctx context.Context, params *s3.GetBucketCorsInput, optFns ...func(*s3.Options)) (*s3.GetBucketCorsOutput, error)
+	PutBucketCors(ctx context.Context, params *s3.PutBucketCorsInput, optFns ...func(*s3.Options)) (*s3.PutBucketCorsOutput, error)
+	DeleteBucketCors(ctx context.Context, params *s3.DeleteBucketCorsInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketCorsOutput, error)
+	GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error)
+	PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error)
+}
+
+// AWSClientProvider returns the EC2 and S3 clients the reconcilers use for a region. The
+// account is selected by the ProviderConfig set on ctx, if any. AWSClientFactory is the real
+// implementation.
+type AWSClientProvider interface {
+	EC2(ctx context.Context, region string) (EC2API, error)
+	S3(ctx context.Context, region string) (S3API, error)
+}
+
+var _ AWSClientProvider = &AWSClientFactory{}
+
+// awsClientMaxAge is how long a cached AWS config is reused before it is loaded again, so
+// changes to the environment or shared config files are picked up without a restart.
+// Credentials themselves are refreshed by the SDK's credentials cache as they expire.
+const awsClientMaxAge = time.Hour
+
+// AWSClientFactory creates the EC2 and S3 clients used by the reconcilers and caches them per
+// region and credential source, so credential resolution and connections are shared across
+// reconciles. It is safe for concurrent use.
+type AWSClientFactory struct {
+	credentials AWSCredentialsOptions
+	reader      client.Reader
+
+	mu      sync.Mutex
+	entries map[awsClientKey]*awsClientEntry
+}
+
+// awsClientKey identifies the clients for one region and credential source
+type awsClientKey struct {
+	region          string
+	secretRef       string
+	roleARN         string
+	externalID      string
+	roleSessionName string
+}
+
+// awsClientEntry caches the clients for one key. Its mutex is held while they are loaded, so
+// a slow config load only holds up the reconciles that need the same clients.
+type awsClientEntry struct {
+	mu      sync.Mutex
+	clients *awsClients
+}
+
+// awsClients are the clients built from one AWS config
+type awsClients struct {
+	loaded time.Time
+	ec2    *ec2.Client
+	s3     *s3.Client
+}
+
+// NewAWSClientFactory returns a factory that uses credentials for the operator's own account.
+// reader is used to read credential Secrets; an uncached reader avoids watching every Secret.
+func NewAWSClientFactory(credentials AWSCredentialsOptions, reader client.Reader) *AWSClientFactory {
+	return &AWSClientFactory{
+		credentials: credentials,
+		reader:      reader,
+		entries:     map[awsClientKey]*awsClientEntry{},
+	}
+}
+
+// Credential Secrets are only read from the operator's own namespace
+// +kubebuilder:rbac:groups="",namespace=system,resources=secrets,verbs=get
+
+// EC2 returns an EC2 client for region. It uses the account of the ProviderConfig set on ctx,
+// or the operator's own credentials without one.
+func (f *AWSClientFactory) EC2(ctx context.Context, region string) (EC2API, error) {
+	clients, err := f.clients(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	return clients.ec2, nil
+}
 
-	// Validate credentials are not empty
-	if accessKey == "" || secretKey == "" {
-		return aws.Config{}, fmt.Errorf("AWS credentials not found: ensure AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables are set")
+// S3 returns an S3 client for region, like EC2
+func (f *AWSClientFactory) S3(ctx context.Context, region string) (S3API, error) {
+	clients, err := f.clients(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	return clients.s3, nil
+}
+
+// clients returns the cached clients for region and the credentials selected by ctx, loading a
+// new AWS config when there are none yet or they are older than awsClientMaxAge
+func (f *AWSClientFactory) clients(ctx context.Context, region string) (*awsClients, error) {
+	opts := f.credentials
+	if provider := awsProviderFrom(ctx); provider != nil {
+		opts = providerCredentials(f.credentials, provider.providerConfig)
+	}
+
+	key := awsClientKey{
+		region:          region,
+		roleARN:         opts.RoleARN,
+		externalID:      opts.ExternalID,
+		roleSessionName: opts.RoleSessionName,
+	}
+	if opts.SecretRef != nil {
+		key.secretRef = opts.SecretRef.String()
+	}
+
+	// f.mu only guards the map, the config is loaded under the lock of the key's entry
+	f.mu.Lock()
+	entry, ok := f.entries[key]
+	if !ok {
+		entry = &awsClientEntry{}
+		f.entries[key] = entry
+	}
+	f.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.clients != nil && time.Since(entry.clients.loaded) < awsClientMaxAge {
+		return entry.clients, nil
+	}
+
+	cfg, err := newAWSConfig(ctx, region, opts, f.reader)
+	if err != nil {
+		return nil, err
 	}
+	entry.clients = &awsClients{
+		loaded: time.Now(),
+		ec2:    ec2.NewFromConfig(cfg),
+		s3:     s3.NewFromConfig(cfg),
+	}
+	return entry.clients, nil
+}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
+// newAWSConfig loads an AWS config for region with the credentials described by opts
+func newAWSConfig(ctx context.Context, region string, opts AWSCredentialsOptions, reader client.Reader) (aws.Config, error) {
+	loadOptions := []func(*config.LoadOptions) error{
 		config.WithRegion(region),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")),
-	)
+	}
+	if opts.SecretRef != nil {
+		if reader == nil {
+			return aws.Config{}, fmt.Errorf("AWS credentials secret %s is set but no Kubernetes client is configured to read it", opts.SecretRef)
+		}
+		loadOptions = append(loadOptions, config.WithCredentialsProvider(aws.NewCredentialsCache(&secretCredentialsProvider{
+			reader: reader,
+			ref:    *opts.SecretRef,
+		})))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
 	if err != nil {
 		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
+	if opts.RoleARN != "" {
+		sessionName := opts.RoleSessionName
+		if sessionName == "" {
+			sessionName = managedByTagValue
+		}
+		assumeRole := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), opts.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = sessionName
+			if opts.ExternalID != "" {
+				o.ExternalID = aws.String(opts.ExternalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(assumeRole)
+	}
+
 	return cfg, nil
 }
+
+// secretCredentialsProvider reads static credentials from a Kubernetes Secret
+type secretCredentialsProvider struct {
+	reader client.Reader
+	ref    types.NamespacedName
+}
+
+// Retrieve implements aws.CredentialsProvider
+func (p *secretCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	secret := &corev1.Secret{}
+	if err := p.reader.Get(ctx, p.ref, secret); err != nil {
+		return aws.Credentials{}, fmt.Errorf("failed to read AWS credentials secret %s: %w", p.ref, err)
+	}
+
+	accessKeyID := string(secret.Data[secretAccessKeyIDKey])
+	secretAccessKey := string(secret.Data[secretSecretAccessKeyKey])
+	if accessKeyID == "" || secretAccessKey == "" {
+		return aws.Credentials{}, fmt.Errorf("AWS credentials secret %s must contain %s and %s",
+			p.ref, secretAccessKeyIDKey, secretSecretAccessKeyKey)
+	}
+
+	return aws.Credentials{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		SessionToken:    string(secret.Data[secretSessionTokenKey]),
+		Source:          "KubernetesSecret",
+		CanExpire:       true,
+		Expires:         time.Now().Add(secretCredentialsTTL),
+	}, nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+)
+
+var _ = Describe("AWS credentials", func() {
+	ctx := context.Background()
+	ref := types.NamespacedName{Namespace: "operator-system", Name: "aws-credentials"}
+
+	It("should read static credentials from a Secret", func() {
+		reader := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ref.Namespace, Name: ref.Name},
+			Data: map[string][]byte{
+				secretAccessKeyIDKey:     []byte("AKIAEXAMPLE"),
+				secretSecretAccessKeyKey: []byte("secret"),
+				secretSessionTokenKey:    []byte("token"),
+			},
+		}).Build()
+
+		creds, err := (&secretCredentialsProvider{reader: reader, ref: ref}).Retrieve(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(creds.AccessKeyID).To(Equal("AKIAEXAMPLE"))
+		Expect(creds.SessionToken).To(Equal("token"))
+		Expect(creds.CanExpire).To(BeTrue())
+	})
+
+	It("should reject a Secret without keys", func() {
+		reader := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ref.Namespace, Name: ref.Name},
+		}).Build()
+
+		_, err := (&secretCredentialsProvider{reader: reader, ref: ref}).Retrieve(ctx)
+		Expect(err).To(MatchError(ContainSubstring(secretAccessKeyIDKey)))
+	})
+})
+
+var _ = Describe("AWS client factory", func() {
+	ctx := context.Background()
+
+	It("should reuse clients per region and credential source", func() {
+		factory := NewAWSClientFactory(AWSCredentialsOptions{}, nil)
+
+		first, err := factory.S3(ctx, "ap-south-1")
+		Expect(err).NotTo(HaveOccurred())
+		again, err := factory.S3(ctx, "ap-south-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(BeIdenticalTo(first))
+
+		otherRegion, err := factory.S3(ctx, "us-west-2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(otherRegion).NotTo(BeIdenticalTo(first))
+
+		providerCtx := context.WithValue(ctx, awsProviderKey{}, &awsProvider{providerConfig: &computev1.ProviderConfig{
+			Spec: computev1.ProviderConfigSpec{
+				AssumeRole: &computev1.AssumeRoleConfig{RoleARN: "arn:aws:iam::123456789012:role/ops"},
+			},
+		}})
+		otherAccount, err := factory.S3(providerCtx, "ap-south-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(otherAccount).NotTo(BeIdenticalTo(first))
+	})
+
+	It("should load the config again once it is too old", func() {
+		factory := NewAWSClientFactory(AWSCredentialsOptions{}, nil)
+
+		first, err := factory.EC2(ctx, "ap-south-1")
+		Expect(err).NotTo(HaveOccurred())
+		for _, entry := range factory.entries {
+			entry.clients.loaded = entry.clients.loaded.Add(-awsClientMaxAge)
+		}
+
+		reloaded, err := factory.EC2(ctx, "ap-south-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reloaded).NotTo(BeIdenticalTo(first))
+	})
+
+	It("should load the config for a key once when it is asked for concurrently", func() {
+		factory := NewAWSClientFactory(AWSCredentialsOptions{}, nil)
+
+		results := make([]EC2API, 8)
+		var wg sync.WaitGroup
+		for i := range results {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+				client, err := factory.EC2(ctx, "ap-south-1")
+				Expect(err).NotTo(HaveOccurred())
+				results[i] = client
+			}()
+		}
+		wg.Wait()
+
+		for _, client := range results {
+			Expect(client).To(BeIdenticalTo(results[0]))
+		}
+	})
+})
+
+// stubAWSClients hands out the same EC2 and S3 clients for every region
+type stubAWSClients struct {
+	ec2 EC2API
+	s3  S3API
+}
+
+func (c *stubAWSClients) EC2(context.Context, string) (EC2API, error) { return c.ec2, nil }
+
+func (c *stubAWSClients) S3(context.Context, string) (S3API, error) { return c.s3, nil }
+
+// terminateRecorder records TerminateInstances calls. Any other EC2 call panics.
+type terminateRecorder struct {
+	EC2API
+	terminated []string
+}
+
+func (r *terminateRecorder) TerminateInstances(_ context.Context, params *ec2.TerminateInstancesInput, _ ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
+	r.terminated = append(r.terminated, params.InstanceIds...)
+	return &ec2.TerminateInstancesOutput{
+		TerminatingInstances: []ec2types.InstanceStateChange{{
+			InstanceId:   aws.String(params.InstanceIds[0]),
+			CurrentState: &ec2types.InstanceState{Name: ec2types.InstanceStateNameShuttingDown},
+		}},
+	}, nil
+}
+
+// missingBucket answers DeleteBucket with NoSuchBucket. Any other S3 call panics.
+type missingBucket struct {
+	S3API
+}
+
+func (missingBucket) DeleteBucket(context.Context, *s3.DeleteBucketInput, ...func(*s3.Options)) (*s3.DeleteBucketOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "NoSuchBucket", Message: "The specified bucket does not exist"}
+}
+
+var _ = Describe("AWS operations", func() {
+	ctx := context.Background()
+
+	It("should terminate the instance recorded in status", func() {
+		recorder := &terminateRecorder{}
+		ec2Instance := &computev1.Ec2instance{Status: computev1.Ec2instanceStatus{InstanceID: "i-0123456789abcdef0"}}
+
+		state, err := deleteEc2Instance(ctx, &stubAWSClients{ec2: recorder}, ec2Instance)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(string(ec2types.InstanceStateNameShuttingDown)))
+		Expect(recorder.terminated).To(Equal([]string{"i-0123456789abcdef0"}))
+	})
+
+	It("should treat a bucket that is already gone as deleted", func() {
+		s3Bucket := &computev1.S3Bucket{Spec: computev1.S3BucketSpec{BucketName: "gone-bucket", Region: "ap-south-1"}}
+
+		deleted, err := deleteS3Bucket(ctx, &stubAWSClients{s3: missingBucket{}}, s3Bucket)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(BeTrue())
+	})
+})
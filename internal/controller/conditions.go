@@ -0,0 +1,71 @@
+package controller
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+)
+
+// Condition reasons shared by both reconcilers
+const (
+	reasonReconcileSuccess = "ReconcileSuccess"
+	reasonInvalidSpec      = "InvalidSpec"
+	reasonCreateFailed     = "CreateFailed"
+	reasonSyncFailed       = "SyncFailed"
+)
+
+// setCondition adds or updates the condition of the given type.
+// LastTransitionTime only moves when the status actually flips, so that
+// repeated reconciles with the same outcome do not rewrite the status.
+func setCondition(conditions *[]computev1.Condition, generation int64, condType string, status bool, reason, message string) {
+	newStatus := string(metav1.ConditionFalse)
+	if status {
+		newStatus = string(metav1.ConditionTrue)
+	}
+
+	for i := range *conditions {
+		existing := &(*conditions)[i]
+		if existing.Type != condType {
+			continue
+		}
+		if existing.Status != newStatus {
+			existing.LastTransitionTime = metav1.Now()
+		}
+		existing.Status = newStatus
+		existing.Reason = reason
+		existing.Message = message
+		existing.ObservedGeneration = generation
+		return
+	}
+
+	*conditions = append(*conditions, computev1.Condition{
+		Type:               condType,
+		Status:             newStatus,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+		LastTransitionTime: metav1.Now(),
+	})
+}
+
+// setSyncFailed records a failed attempt to reach the desired state. Error is reserved for
+// failures retrying will not fix, so it stays False here and the cause goes into the Synced
+// message. Ready is left as it was: the AWS resource is as usable as it was before the attempt.
+func setSyncFailed(conditions *[]computev1.Condition, generation int64, reason string, err error) {
+	setCondition(conditions, generation, computev1.ConditionSynced, false, reason, err.Error())
+	setCondition(conditions, generation, computev1.ConditionError, false, reason, "")
+}
+
+// setTerminalError records an error that will not go away without user action
+func setTerminalError(conditions *[]computev1.Condition, generation int64, reason, message string) {
+	setCondition(conditions, generation, computev1.ConditionReady, false, reason, message)
+	setCondition(conditions, generation, computev1.ConditionSynced, false, reason, message)
+	setCondition(conditions, generation, computev1.ConditionError, true, reason, message)
+}
+
+// setSynced records a successful reconcile, with ready telling whether the resource is usable
+func setSynced(conditions *[]computev1.Condition, generation int64, ready bool, reason, message string) {
+	setCondition(conditions, generation, computev1.ConditionReady, ready, reason, message)
+	setCondition(conditions, generation, computev1.ConditionSynced, true, reasonReconcileSuccess, "")
+	setCondition(conditions, generation, computev1.ConditionError, false, reasonReconcileSuccess, "")
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+)
+
+var _ = Describe("Conditions", func() {
+	// triplet returns the status of Ready, Synced and Error, in that order
+	triplet := func(conditions []computev1.Condition) []string {
+		var statuses []string
+		for _, condType := range []string{computev1.ConditionReady, computev1.ConditionSynced, computev1.ConditionError} {
+			condition := findCondition(conditions, condType)
+			Expect(condition.Type).To(Equal(condType), "condition %s is missing", condType)
+			statuses = append(statuses, condition.Status)
+		}
+		return statuses
+	}
+
+	It("should only report Error for failures retrying will not fix", func() {
+		var conditions []computev1.Condition
+
+		setSynced(&conditions, 1, true, "InstanceRunning", "")
+		Expect(triplet(conditions)).To(Equal([]string{"True", "True", "False"}))
+
+		setSyncFailed(&conditions, 2, reasonSyncFailed, errors.New("throttled"))
+		Expect(triplet(conditions)).To(Equal([]string{"True", "False", "False"}))
+		synced := findCondition(conditions, computev1.ConditionSynced)
+		Expect(synced.Reason).To(Equal(reasonSyncFailed))
+		Expect(synced.Message).To(Equal("throttled"))
+
+		setTerminalError(&conditions, 3, reasonInvalidSpec, "bad spec")
+		Expect(triplet(conditions)).To(Equal([]string{"False", "False", "True"}))
+		Expect(findCondition(conditions, computev1.ConditionError).Message).To(Equal("bad spec"))
+
+		for _, condition := range conditions {
+			Expect(condition.ObservedGeneration).To(Equal(int64(3)))
+		}
+	})
+
+	It("should only move LastTransitionTime when the status flips", func() {
+		var conditions []computev1.Condition
+		setSynced(&conditions, 1, true, "InstanceRunning", "")
+
+		past := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+		for i := range conditions {
+			conditions[i].LastTransitionTime = past
+		}
+
+		setSynced(&conditions, 2, true, "InstanceStopped", "")
+		Expect(findCondition(conditions, computev1.ConditionReady).LastTransitionTime).To(Equal(past))
+		Expect(findCondition(conditions, computev1.ConditionReady).Reason).To(Equal("InstanceStopped"))
+
+		setSyncFailed(&conditions, 2, reasonSyncFailed, errors.New("throttled"))
+		Expect(findCondition(conditions, computev1.ConditionSynced).LastTransitionTime).NotTo(Equal(past))
+		Expect(findCondition(conditions, computev1.ConditionError).LastTransitionTime).To(Equal(past))
+	})
+})
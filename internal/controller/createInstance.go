@@ -3,7 +3,6 @@ package controller
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
@@ -20,69 +19,117 @@ func stringOrNil(s string) *string {
 	return aws.String(s)
 }
 
-func createEc2Instance(ctx context.Context, ec2Instance *computev1.Ec2instance) (createdInstanceInfo *computev1.CreatedInstanceInfo, err error) {
+func createEc2Instance(ctx context.Context, clients AWSClientProvider, ec2Instance *computev1.Ec2instance) (createdInstanceInfo *computev1.CreatedInstanceInfo, err error) {
 	l := log.FromContext(ctx) // Use context-aware logger instead of global logger
 
 	l.Info("=== STARTING EC2 INSTANCE CREATION ===",
 		"ami", ec2Instance.Spec.AMIId,
 		"instanceType", ec2Instance.Spec.InstanceType,
-		"region", ec2Instance.Spec.Region)
+		"region", ec2Instance.Status.Region)
 
-	// Get AWS config and create EC2 client
-	cfg, err := getAWSConfig(ec2Instance.Spec.Region)
+	ec2Client, err := clients.EC2(ctx, ec2Instance.Status.Region)
 	if err != nil {
-		l.Error(err, "Failed to get AWS config")
-		return nil, fmt.Errorf("failed to get AWS config: %w", err)
+		l.Error(err, "Failed to create EC2 client")
+		return nil, fmt.Errorf("failed to create EC2 client: %w", err)
 	}
-	ec2Client := ec2.NewFromConfig(cfg)
 
-	// create the input for the run instances
+	// A previous reconcile may have launched the instance and then failed to record its ID
+	// in status. Look for an instance tagged with our UID before launching another one.
+	existingInstance, err := findInstanceByOwner(ctx, ec2Client, ec2Instance)
+	if err != nil {
+		l.Error(err, "Failed to look up existing instance for this resource")
+		return nil, err
+	}
+	if existingInstance != nil {
+		l.Info("=== FOUND INSTANCE ALREADY LAUNCHED FOR THIS RESOURCE, NOT LAUNCHING ANOTHER ===",
+			"InstanceID", existingInstance.InstanceId,
+			"State", existingInstance.State)
+		return existingInstance, nil
+	}
+
+	// create the input for the run instances. The client token makes RunInstances itself
+	// idempotent: retrying with the same token returns the instance launched the first time.
 	runInput := &ec2.RunInstancesInput{
 		ImageId:      aws.String(ec2Instance.Spec.AMIId),
 		InstanceType: ec2types.InstanceType(ec2Instance.Spec.InstanceType),
 		MinCount:     aws.Int32(1),
 		MaxCount:     aws.Int32(1),
 		KeyName:      stringOrNil(ec2Instance.Spec.KeyPair),
-		SubnetId:     stringOrNil(ec2Instance.Spec.Subnet),
 		UserData:     stringOrNil(ec2Instance.Spec.UserData),
+		ClientToken:  stringOrNil(string(ec2Instance.UID)),
+	}
+
+	// Make sure the subnet and availability zone agree before launching anything
+	if err := validatePlacement(ctx, ec2Client, ec2Instance); err != nil {
+		l.Error(err, "Invalid instance placement")
+		return nil, err
+	}
+
+	// Hibernation can only be enabled at launch, so do it whenever it might be wanted later
+	if ec2Instance.Spec.Hibernation || ec2Instance.Spec.PowerState == computev1.PowerStateHibernated {
+		runInput.HibernationOptions = &ec2types.HibernationOptionsRequest{
+			Configured: aws.Bool(true),
+		}
+	}
+
+	if ec2Instance.Spec.AvailabilityZone != "" {
+		runInput.Placement = &ec2types.Placement{
+			AvailabilityZone: aws.String(ec2Instance.Spec.AvailabilityZone),
+		}
 	}
 
-	// Add security groups if provided
-	if len(ec2Instance.Spec.SecurityGroups) > 0 {
+	// When a subnet is given or a public IP is requested, the primary network interface is
+	// declared explicitly so the public IP follows the spec instead of the subnet default.
+	// Subnet and security groups then have to be set on the interface, not on the request.
+	if ec2Instance.Spec.Subnet != "" || ec2Instance.Spec.AssociatePublicIP {
+		networkInterface := ec2types.InstanceNetworkInterfaceSpecification{
+			DeviceIndex:              aws.Int32(0),
+			SubnetId:                 stringOrNil(ec2Instance.Spec.Subnet),
+			AssociatePublicIpAddress: aws.Bool(ec2Instance.Spec.AssociatePublicIP),
+			DeleteOnTermination:      aws.Bool(true),
+		}
+		if len(ec2Instance.Spec.SecurityGroups) > 0 {
+			networkInterface.Groups = ec2Instance.Spec.SecurityGroups
+		}
+		runInput.NetworkInterfaces = []ec2types.InstanceNetworkInterfaceSpecification{networkInterface}
+	} else if len(ec2Instance.Spec.SecurityGroups) > 0 {
+		// Add security groups if provided
 		runInput.SecurityGroupIds = ec2Instance.Spec.SecurityGroups
 	}
 
-	// Prepare tags - always include a Name tag based on the Kubernetes resource name
-	tags := []ec2types.Tag{
-		{
-			Key:   aws.String("Name"),
-			Value: aws.String(ec2Instance.Name), // Use Kubernetes resource name
-		},
-		{
-			Key:   aws.String("ManagedBy"),
-			Value: aws.String("ec2instance-operator"),
-		},
-		{
-			Key:   aws.String("Namespace"),
-			Value: aws.String(ec2Instance.Namespace),
-		},
+	// Add block device mappings for the root volume override and any additional volumes
+	blockDeviceMappings, err := buildBlockDeviceMappings(ctx, ec2Client, ec2Instance)
+	if err != nil {
+		l.Error(err, "Failed to build block device mappings")
+		return nil, err
+	}
+	if len(blockDeviceMappings) > 0 {
+		runInput.BlockDeviceMappings = blockDeviceMappings
 	}
 
-	// Add user-defined tags from spec
-	for key, value := range ec2Instance.Spec.Tags {
+	// Tag with the ProviderConfig defaults, labels and spec tags, with the Name and ownership
+	// tags always winning, so every key appears once
+	desiredTags := resourceTags(ec2Instance, providerTags(ctx), ec2Instance.Spec.Tags)
+	tags := make([]ec2types.Tag, 0, len(desiredTags))
+	for _, key := range sortedTagKeys(desiredTags) {
 		tags = append(tags, ec2types.Tag{
 			Key:   aws.String(key),
-			Value: aws.String(value),
+			Value: aws.String(desiredTags[key]),
 		})
 	}
 
-	// Add tags to the instance creation request
+	// Add tags to the instance creation request. The volumes get the same tags
+	// so it is possible to tell which disks belong to which custom resource.
 	if len(tags) > 0 {
 		runInput.TagSpecifications = []ec2types.TagSpecification{
 			{
 				ResourceType: ec2types.ResourceTypeInstance,
 				Tags:         tags,
 			},
+			{
+				ResourceType: ec2types.ResourceTypeVolume,
+				Tags:         tags,
+			},
 		}
 	}
 
@@ -97,91 +144,163 @@ func createEc2Instance(ctx context.Context, ec2Instance *computev1.Ec2instance)
 
 	if len(result.Instances) == 0 {
 		l.Error(nil, "No instances returned in RunInstanceOutput")
-		fmt.Println("No instances returned in RunInstanceOutput")
-		return nil, nil
+		return nil, fmt.Errorf("no instances returned by RunInstances")
 	}
 
-	// Till here, the instance is created and we have
-	// Instance ID, private dns and IP, instance type and image id.
+	// Till here, the instance is launched and we have its instance ID. We do not wait
+	// for it to be running here: the reconciler records the ID right away and polls
+	// DescribeInstances on later reconciles until the public IP and DNS show up.
 	inst := result.Instances[0]
-	l.Info("=== EC2 INSTANCE CREATED SUCCESSFULLY ===",
-		"InstanceID", *inst.InstanceId,
-		"State", inst.State.Name)
+	createdInstanceInfo = instanceInfo(inst)
 
-	// Now we need to wait for the instance to be running and then get the public ip and dns
-	l.Info("=== WAITING FOR INSTANCE TO BE IN 'running' STATE ===")
+	l.Info("=== EC2 INSTANCE LAUNCHED ===",
+		"InstanceID", createdInstanceInfo.InstanceId,
+		"State", createdInstanceInfo.State,
+	)
 
-	runWaiter := ec2.NewInstanceRunningWaiter(ec2Client)
-	maxWaitTime := 3 * time.Minute
+	return createdInstanceInfo, nil
+}
 
-	err = runWaiter.Wait(ctx, &ec2.DescribeInstancesInput{
-		InstanceIds: []string{*inst.InstanceId},
-	}, maxWaitTime) //we wait for instance to be in running state
+// findInstanceByOwner returns the live instance tagged with the resource's UID, if any.
+// Terminated and shutting-down instances are ignored.
+func findInstanceByOwner(ctx context.Context, ec2Client EC2API, ec2Instance *computev1.Ec2instance) (*computev1.CreatedInstanceInfo, error) {
+	if ec2Instance.UID == "" {
+		return nil, nil
+	}
 
+	describeResult, err := ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{
+				Name:   aws.String("tag:" + ownerUIDTagKey),
+				Values: []string{string(ec2Instance.UID)},
+			},
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: []string{"pending", "running", "stopping", "stopped"},
+			},
+		},
+	})
 	if err != nil {
-		l.Error(err, "Failed to wait for the instance to come to running state")
-		return nil, fmt.Errorf("failed to wait for the instance to be in running: %w", err)
+		return nil, fmt.Errorf("failed to describe instances owned by %s: %w", ec2Instance.UID, err)
 	}
 
-	// After creating the instance, we waited and now we describe to
-	// 1. Get the public IP and dns as it takes some time for it
-	// 2. Getting the state of the instance.
-	// We do this so we can send the instance's state to the status of the custom resource. for user to see with kubectl get ec2instances
-	l.Info("=== CALLING AWS DescribeInstances API TO GET INSTANCE DETAILS ===")
-	describeInput := &ec2.DescribeInstancesInput{
-		InstanceIds: []string{*inst.InstanceId},
+	for _, reservation := range describeResult.Reservations {
+		if len(reservation.Instances) > 0 {
+			return instanceInfo(reservation.Instances[0]), nil
+		}
 	}
+	return nil, nil
+}
 
-	describeResult, err := ec2Client.DescribeInstances(ctx, describeInput)
-	if err != nil {
-		l.Error(err, "Failed to describe EC2 instance")
-		return nil, fmt.Errorf("failed to describe EC2 instance: %w", err)
+// validatePlacement rejects a spec whose subnet lives in a different availability zone
+// than the one requested, instead of letting RunInstances fail or silently pick one.
+func validatePlacement(ctx context.Context, ec2Client EC2API, ec2Instance *computev1.Ec2instance) error {
+	if ec2Instance.Spec.Subnet == "" || ec2Instance.Spec.AvailabilityZone == "" {
+		return nil
 	}
 
-	// You get "invalid memory address or nil pointer dereference" here if any of the following are true:
-	// - result.Instances is nil or has length 0
-	// - Any of the pointer fields (e.g., PublicIpAddress, PrivateIpAddress, etc.) are nil
-
-	// To avoid this, always check for nil and length before dereferencing:
+	subnetResult, err := ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+		SubnetIds: []string{ec2Instance.Spec.Subnet},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe subnet %s: %w", ec2Instance.Spec.Subnet, err)
+	}
+	if len(subnetResult.Subnets) == 0 {
+		return newInvalidSpecError("subnet %s not found", ec2Instance.Spec.Subnet)
+	}
 
-	// Wait for a bit to allow instance fields to be populated
+	subnetZone := aws.ToString(subnetResult.Subnets[0].AvailabilityZone)
+	if subnetZone != ec2Instance.Spec.AvailabilityZone {
+		return newInvalidSpecError("subnet %s is in availability zone %s but availabilityZone is %s",
+			ec2Instance.Spec.Subnet, subnetZone, ec2Instance.Spec.AvailabilityZone)
+	}
+	return nil
+}
 
-	fmt.Printf("Private IP of the instance: %v", derefString(inst.PrivateIpAddress))
-	fmt.Printf("State of the instance: %v", describeResult.Reservations[0].Instances[0].State.Name)
-	fmt.Printf("Private DNS of the instance: %v", derefString(inst.PrivateDnsName))
-	fmt.Printf("Instance ID of the instance: %v", derefString(inst.InstanceId))
-	fmt.Println("Instance Type of the instance: ", inst.InstanceType)
-	fmt.Printf("Image ID of the instance: %v", derefString(inst.ImageId))
-	fmt.Printf("Key Name of the instance: %v", derefString(inst.KeyName))
+// buildBlockDeviceMappings converts the storage section of the spec into EC2 block
+// device mappings. The root volume is only overridden when a size or type is set,
+// in which case the AMI is described to find its root device name unless one is given.
+func buildBlockDeviceMappings(ctx context.Context, ec2Client EC2API, ec2Instance *computev1.Ec2instance) ([]ec2types.BlockDeviceMapping, error) {
+	storage := ec2Instance.Spec.Storage
+	var mappings []ec2types.BlockDeviceMapping
 
-	// block until the instance is running
-	// blockUntilInstanceRunning(ctx, ec2Instance.Status.InstanceID, ec2Instance)
+	root := storage.RootVolume
+	if root.Size > 0 || root.Type != "" || root.Encrypted {
+		deviceName := root.DeviceName
+		if deviceName == "" {
+			imageResult, err := ec2Client.DescribeImages(ctx, &ec2.DescribeImagesInput{
+				ImageIds: []string{ec2Instance.Spec.AMIId},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe AMI %s: %w", ec2Instance.Spec.AMIId, err)
+			}
+			if len(imageResult.Images) == 0 || imageResult.Images[0].RootDeviceName == nil {
+				return nil, fmt.Errorf("unable to determine root device name for AMI %s", ec2Instance.Spec.AMIId)
+			}
+			deviceName = *imageResult.Images[0].RootDeviceName
+		}
+		mappings = append(mappings, ebsBlockDeviceMapping(deviceName, root))
+	}
 
-	// Get the instance details safely (public IP/DNS might be nil for private subnets)
-	instance := describeResult.Reservations[0].Instances[0]
+	// Additional volumes default to /dev/sdf, /dev/sdg, ... as recommended by AWS, skipping
+	// names the spec already uses for another volume
+	used := map[string]bool{root.DeviceName: true}
+	for _, volume := range storage.AdditionalVolumes {
+		used[volume.DeviceName] = true
+	}
+	next := 'f'
 
-	createdInstanceInfo = &computev1.CreatedInstanceInfo{
-		InstanceId: *instance.InstanceId,
-		State:      string(instance.State.Name),
-		PublicIP:   derefString(instance.PublicIpAddress),
-		PrivateIP:  derefString(instance.PrivateIpAddress),
-		PublicDNS:  derefString(instance.PublicDnsName),
-		PrivateDNS: derefString(instance.PrivateDnsName),
+	for i, volume := range storage.AdditionalVolumes {
+		if volume.Size <= 0 {
+			return nil, newInvalidSpecError("additional volume %d must have a size greater than 0", i)
+		}
+		deviceName := volume.DeviceName
+		for deviceName == "" {
+			if next > 'z' {
+				return nil, newInvalidSpecError("no device name left for additional volume %d, set deviceName explicitly", i)
+			}
+			if name := fmt.Sprintf("/dev/sd%c", next); !used[name] {
+				deviceName = name
+			}
+			next++
+		}
+		mappings = append(mappings, ebsBlockDeviceMapping(deviceName, volume))
 	}
 
-	l.Info("=== EC2 INSTANCE CREATION COMPLETED ===",
-		"InstanceID", createdInstanceInfo.InstanceId,
-		"State", createdInstanceInfo.State,
-		"PublicIP", createdInstanceInfo.PublicIP,
-	)
+	return mappings, nil
+}
 
-	return createdInstanceInfo, nil
+// ebsBlockDeviceMapping builds a single EBS mapping that is deleted along with the instance
+func ebsBlockDeviceMapping(deviceName string, volume computev1.VolumeConfig) ec2types.BlockDeviceMapping {
+	ebs := &ec2types.EbsBlockDevice{
+		DeleteOnTermination: aws.Bool(true),
+	}
+	if volume.Size > 0 {
+		ebs.VolumeSize = aws.Int32(volume.Size)
+	}
+	if volume.Type != "" {
+		ebs.VolumeType = ec2types.VolumeType(volume.Type)
+	}
+	if volume.Encrypted {
+		ebs.Encrypted = aws.Bool(true)
+	}
+	return ec2types.BlockDeviceMapping{
+		DeviceName: aws.String(deviceName),
+		Ebs:        ebs,
+	}
 }
 
-// derefString is a helper function to safely dereference *string
-func derefString(s *string) string {
-	if s != nil {
-		return *s
+// attachedVolumes extracts the EBS volume IDs from the instance's block device mappings
+func attachedVolumes(mappings []ec2types.InstanceBlockDeviceMapping) []computev1.AttachedVolume {
+	var volumes []computev1.AttachedVolume
+	for _, mapping := range mappings {
+		if mapping.Ebs == nil || mapping.Ebs.VolumeId == nil {
+			continue
+		}
+		volumes = append(volumes, computev1.AttachedVolume{
+			DeviceName: aws.ToString(mapping.DeviceName),
+			VolumeID:   *mapping.Ebs.VolumeId,
+		})
 	}
-	return "<nil>"
+	return volumes
 }
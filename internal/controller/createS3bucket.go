@@ -11,49 +11,75 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-func createS3Bucket(ctx context.Context, s3Bucket *computev1.S3Bucket) (createdBucketInfo *computev1.CreatedBucketInfo, err error) {
+func createS3Bucket(ctx context.Context, clients AWSClientProvider, s3Bucket *computev1.S3Bucket) (createdBucketInfo *computev1.CreatedBucketInfo, err error) {
 	l := log.FromContext(ctx) // Use context-aware logger instead of global logger
 
 	l.Info("=== STARTING S3 BUCKET CREATION ===",
 		"bucketName", s3Bucket.Spec.BucketName,
-		"region", s3Bucket.Spec.Region,
+		"region", s3Bucket.Status.Region,
 		"storageClass", s3Bucket.Spec.StorageClass)
 
-	// Get AWS config and create S3 client
-	cfg, err := getAWSConfig(s3Bucket.Spec.Region)
+	s3Client, err := clients.S3(ctx, s3Bucket.Status.Region)
 	if err != nil {
-		l.Error(err, "Failed to get AWS config")
-		return nil, fmt.Errorf("failed to get AWS config: %w", err)
+		l.Error(err, "Failed to create S3 client")
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
 	}
-	s3Client := s3.NewFromConfig(cfg)
 
 	// Prepare the CreateBucket input
 	createBucketInput := &s3.CreateBucketInput{
 		Bucket: aws.String(s3Bucket.Spec.BucketName),
 	}
 
-	// Add ACL if specified
-	if s3Bucket.Spec.ACL != "" {
-		createBucketInput.ACL = s3types.BucketCannedACL(s3Bucket.Spec.ACL)
+	// The ACL is applied by configureS3Bucket once Block Public Access and object ownership
+	// allow it. Passing it here fails on accounts where ACLs are disabled by default.
+	if s3Bucket.Spec.ObjectOwnership != "" {
+		createBucketInput.ObjectOwnership = s3types.ObjectOwnership(s3Bucket.Spec.ObjectOwnership)
 	}
 
 	// For regions other than us-east-1, we need to specify LocationConstraint
-	if s3Bucket.Spec.Region != "us-east-1" {
+	if s3Bucket.Status.Region != "us-east-1" {
 		createBucketInput.CreateBucketConfiguration = &s3types.CreateBucketConfiguration{
-			LocationConstraint: s3types.BucketLocationConstraint(s3Bucket.Spec.Region),
+			LocationConstraint: s3types.BucketLocationConstraint(s3Bucket.Status.Region),
 		}
 	}
 
 	l.Info("Creating S3 bucket with configuration",
 		"bucketName", s3Bucket.Spec.BucketName,
-		"region", s3Bucket.Spec.Region,
-		"acl", s3Bucket.Spec.ACL)
+		"region", s3Bucket.Status.Region,
+		"objectOwnership", s3Bucket.Spec.ObjectOwnership)
 
 	// Create the S3 bucket
 	createOutput, err := s3Client.CreateBucket(ctx, createBucketInput)
-	if err != nil {
+	switch {
+	case awsErrorCode(err) == "BucketAlreadyOwnedByYou":
+		// A previous reconcile may have created the bucket and failed before recording it in
+		// status. Only a bucket carrying this resource's UID is ours, don't silently take over
+		// a bucket someone else in the account may rely on.
+		tags, tagErr := getBucketTags(ctx, s3Client, s3Bucket.Spec.BucketName)
+		if tagErr != nil {
+			return nil, tagErr
+		}
+		if s3Bucket.UID == "" || tags[ownerUIDTagKey] != string(s3Bucket.UID) {
+			return nil, newInvalidSpecError("bucket %s already exists in this account, set spec.adopt to manage it",
+				s3Bucket.Spec.BucketName)
+		}
+		l.Info("S3 bucket was already created for this resource", "bucketName", s3Bucket.Spec.BucketName)
+		createOutput = &s3.CreateBucketOutput{}
+	case err != nil:
 		l.Error(err, "Failed to create S3 bucket")
 		return nil, fmt.Errorf("failed to create S3 bucket: %w", err)
+	default:
+		// Tag the owner right away, so the bucket is recognised as ours even if recording it
+		// in status fails. configureS3Bucket applies the full tag set afterwards.
+		if _, err := s3Client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+			Bucket: aws.String(s3Bucket.Spec.BucketName),
+			Tagging: &s3types.Tagging{TagSet: []s3types.Tag{{
+				Key:   aws.String(ownerUIDTagKey),
+				Value: aws.String(string(s3Bucket.UID)),
+			}}},
+		}); err != nil {
+			return nil, fmt.Errorf("failed to tag S3 bucket %s with its owner: %w", s3Bucket.Spec.BucketName, err)
+		}
 	}
 
 	l.Info("=== S3 BUCKET CREATED SUCCESSFULLY ===",
@@ -68,6 +94,6 @@ func createS3Bucket(ctx context.Context, s3Bucket *computev1.S3Bucket) (createdB
 		BucketName: s3Bucket.Spec.BucketName,
 		BucketARN:  bucketARN,
 		Location:   aws.ToString(createOutput.Location),
-		Region:     s3Bucket.Spec.Region,
+		Region:     s3Bucket.Status.Region,
 	}, nil
 }
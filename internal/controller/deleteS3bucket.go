@@ -2,54 +2,137 @@ package controller
 
 import (
 	"context"
+	"fmt"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
-	"time"
 )
 
-func deleteS3Bucket(ctx context.Context, s3Bucket *computev1.S3Bucket) (bool, error) {
+// emptyBucketPageSize is how many object versions are listed and deleted per batch.
+// DeleteObjects accepts at most 1000 keys.
+const emptyBucketPageSize = 1000
+
+// emptyBucketBatchesPerCall bounds how long a single emptyS3Bucket call runs, so large
+// buckets are emptied over several reconciles with progress reported in between
+const emptyBucketBatchesPerCall = 5
+
+// deleteS3Bucket asks AWS to delete the bucket and reports whether it is gone, without waiting
+// for the deletion to finish. Until HeadBucket stops finding the bucket the reconciler calls it
+// again on later reconciles.
+func deleteS3Bucket(ctx context.Context, clients AWSClientProvider, s3Bucket *computev1.S3Bucket) (bool, error) {
 	l := logf.FromContext(ctx)
 
 	l.Info("Deleting S3 bucket", "bucketARN", s3Bucket.Status.BucketARN)
 
-	// Get AWS config and create S3 client
-	cfg, err := getAWSConfig(s3Bucket.Spec.Region)
+	s3Client, err := clients.S3(ctx, s3Bucket.Status.Region)
 	if err != nil {
-		l.Error(err, "Failed to get AWS config")
+		l.Error(err, "Failed to create S3 client")
 		return false, err
 	}
-	s3Client := s3.NewFromConfig(cfg)
 
 	_, err = s3Client.DeleteBucket(ctx, &s3.DeleteBucketInput{
 		Bucket: aws.String(s3Bucket.Spec.BucketName),
 	})
 	if err != nil {
+		if awsErrorCode(err) == "NoSuchBucket" {
+			l.Info("S3 bucket is already gone", "bucketARN", s3Bucket.Status.BucketARN)
+			return true, nil
+		}
 		l.Error(err, "Failed to delete S3 bucket")
 		return false, err
 	}
 
 	l.Info("S3 bucket deletion initiated", "bucketARN", s3Bucket.Status.BucketARN)
 
-	// Wait for the bucket to be deleted
-	waiter := s3.NewBucketNotExistsWaiter(s3Client)
-	maxWaitTime := 5 * time.Minute
-	waitParams := &s3.HeadBucketInput{
+	if _, err := s3Client.HeadBucket(ctx, &s3.HeadBucketInput{
 		Bucket: aws.String(s3Bucket.Spec.BucketName),
+	}); err != nil {
+		if code := awsErrorCode(err); code == "NotFound" || code == "NoSuchBucket" {
+			l.Info("S3 bucket successfully deleted", "bucketARN", s3Bucket.Status.BucketARN)
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to check deletion of S3 bucket %s: %w", s3Bucket.Spec.BucketName, err)
 	}
 
-	l.Info("Waiting for the S3 bucket to be deleted",
-		"bucketARN", s3Bucket.Status.BucketARN,
-		"maxWaitTime", maxWaitTime)
+	l.Info("S3 bucket still visible, waiting for the deletion to propagate", "bucketARN", s3Bucket.Status.BucketARN)
+	return false, nil
+}
 
-	err = waiter.Wait(ctx, waitParams, maxWaitTime)
+// emptyS3Bucket deletes object versions and delete markers in batches and returns how many it
+// removed and whether the bucket is now empty. It stops after emptyBucketBatchesPerCall
+// batches, so callers should call it again until it reports the bucket empty.
+func emptyS3Bucket(ctx context.Context, clients AWSClientProvider, s3Bucket *computev1.S3Bucket) (int64, bool, error) {
+	l := logf.FromContext(ctx)
+
+	l.Info("Emptying S3 bucket", "bucketARN", s3Bucket.Status.BucketARN)
+
+	s3Client, err := clients.S3(ctx, s3Bucket.Status.Region)
 	if err != nil {
-		l.Error(err, "Failed to wait for S3 bucket deletion",
-			"bucketARN", s3Bucket.Status.BucketARN)
-		return false, err
+		l.Error(err, "Failed to create S3 client")
+		return 0, false, err
 	}
 
-	l.Info("S3 bucket successfully deleted", "bucketARN", s3Bucket.Status.BucketARN)
-	return true, nil
+	var deleted int64
+	for batch := 0; batch < emptyBucketBatchesPerCall; batch++ {
+		// Every batch deletes what it lists, so the first page is always what is left
+		listOutput, err := s3Client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket:  aws.String(s3Bucket.Spec.BucketName),
+			MaxKeys: aws.Int32(emptyBucketPageSize),
+		})
+		if err != nil {
+			if awsErrorCode(err) == "NoSuchBucket" {
+				return deleted, true, nil
+			}
+			return deleted, false, fmt.Errorf("failed to list object versions of S3 bucket %s: %w", s3Bucket.Spec.BucketName, err)
+		}
+
+		objects := objectIdentifiers(listOutput)
+		if len(objects) == 0 {
+			l.Info("S3 bucket is empty", "bucketARN", s3Bucket.Status.BucketARN, "objectsDeleted", deleted)
+			return deleted, true, nil
+		}
+
+		deleteOutput, err := s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s3Bucket.Spec.BucketName),
+			Delete: &s3types.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return deleted, false, fmt.Errorf("failed to delete objects from S3 bucket %s: %w", s3Bucket.Spec.BucketName, err)
+		}
+		// Quiet mode only reports the keys that failed
+		deleted += int64(len(objects) - len(deleteOutput.Errors))
+		if len(deleteOutput.Errors) > 0 {
+			failed := deleteOutput.Errors[0]
+			return deleted, false, fmt.Errorf("failed to delete %d objects from S3 bucket %s, first error on %s: %s",
+				len(deleteOutput.Errors), s3Bucket.Spec.BucketName, aws.ToString(failed.Key), aws.ToString(failed.Message))
+		}
+
+		l.Info("Deleted batch of object versions", "bucketARN", s3Bucket.Status.BucketARN, "count", len(objects))
+	}
+
+	return deleted, false, nil
+}
+
+// objectIdentifiers collects the versions and delete markers of a ListObjectVersions page
+func objectIdentifiers(listOutput *s3.ListObjectVersionsOutput) []s3types.ObjectIdentifier {
+	objects := make([]s3types.ObjectIdentifier, 0, len(listOutput.Versions)+len(listOutput.DeleteMarkers))
+	for _, version := range listOutput.Versions {
+		objects = append(objects, s3types.ObjectIdentifier{
+			Key:       version.Key,
+			VersionId: version.VersionId,
+		})
+	}
+	for _, marker := range listOutput.DeleteMarkers {
+		objects = append(objects, s3types.ObjectIdentifier{
+			Key:       marker.Key,
+			VersionId: marker.VersionId,
+		})
+	}
+	return objects
 }
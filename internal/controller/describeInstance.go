@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// describeEc2Instance fetches the current state of the instance recorded in status.
+// It returns nil info and no error when AWS no longer knows about the instance.
+func describeEc2Instance(ctx context.Context, clients AWSClientProvider, ec2Instance *computev1.Ec2instance) (*computev1.CreatedInstanceInfo, error) {
+	l := logf.FromContext(ctx)
+
+	ec2Client, err := clients.EC2(ctx, ec2Instance.Status.Region)
+	if err != nil {
+		l.Error(err, "Failed to create EC2 client")
+		return nil, fmt.Errorf("failed to create EC2 client: %w", err)
+	}
+
+	describeResult, err := ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{ec2Instance.Status.InstanceID},
+	})
+	if err != nil {
+		if awsErrorCode(err) == "InvalidInstanceID.NotFound" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to describe EC2 instance: %w", err)
+	}
+
+	if len(describeResult.Reservations) == 0 || len(describeResult.Reservations[0].Instances) == 0 {
+		return nil, nil
+	}
+
+	return instanceInfo(describeResult.Reservations[0].Instances[0]), nil
+}
+
+// instanceInfo converts an AWS instance description into the info we report in status.
+// Public IP/DNS might be nil for private subnets or stopped instances.
+func instanceInfo(instance ec2types.Instance) *computev1.CreatedInstanceInfo {
+	info := &computev1.CreatedInstanceInfo{
+		InstanceId:   aws.ToString(instance.InstanceId),
+		InstanceType: string(instance.InstanceType),
+		PublicIP:     aws.ToString(instance.PublicIpAddress),
+		PrivateIP:    aws.ToString(instance.PrivateIpAddress),
+		PublicDNS:    aws.ToString(instance.PublicDnsName),
+		PrivateDNS:   aws.ToString(instance.PrivateDnsName),
+		Volumes:      attachedVolumes(instance.BlockDeviceMappings),
+	}
+	if instance.State != nil {
+		info.State = string(instance.State.Name)
+	}
+	if instance.LaunchTime != nil {
+		info.LaunchTime = instance.LaunchTime.Format(time.RFC3339)
+	}
+	return info
+}
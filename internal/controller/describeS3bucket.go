@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Fields reported in status.drift
+const (
+	driftVersioning = "versioning"
+	driftEncryption = "encryption"
+	driftTags       = "tags"
+)
+
+// describeS3Bucket checks that the bucket still exists and compares the location, versioning,
+// encryption and tags AWS reports with the spec. It returns false if the bucket is gone, and
+// the names of the fields that drifted. A bucket in another region than spec.region is an
+// invalid spec, since buckets cannot move between regions.
+func describeS3Bucket(ctx context.Context, clients AWSClientProvider, s3Bucket *computev1.S3Bucket) (bool, []string, error) {
+	l := logf.FromContext(ctx)
+	bucketName := s3Bucket.Spec.BucketName
+
+	s3Client, err := clients.S3(ctx, s3Bucket.Status.Region)
+	if err != nil {
+		l.Error(err, "Failed to create S3 client")
+		return false, nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	if _, err := s3Client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(bucketName),
+	}); err != nil {
+		if code := awsErrorCode(err); code == "NotFound" || code == "NoSuchBucket" {
+			return false, nil, nil
+		}
+		return false, nil, fmt.Errorf("failed to check S3 bucket %s: %w", bucketName, err)
+	}
+
+	locationOutput, err := s3Client.GetBucketLocation(ctx, &s3.GetBucketLocationInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		return true, nil, fmt.Errorf("failed to get location of S3 bucket %s: %w", bucketName, err)
+	}
+	// Buckets in us-east-1 report an empty location constraint
+	bucketRegion := string(locationOutput.LocationConstraint)
+	if bucketRegion == "" {
+		bucketRegion = "us-east-1"
+	}
+	if bucketRegion != s3Bucket.Status.Region {
+		return true, nil, newInvalidSpecError("bucket %s is in region %s but the resource is in %s, buckets cannot change region",
+			bucketName, bucketRegion, s3Bucket.Status.Region)
+	}
+
+	var drift []string
+
+	versioning, err := getBucketVersioning(ctx, s3Client, bucketName)
+	if err != nil {
+		return true, nil, err
+	}
+	if !versioningMatches(versioning, s3Bucket.Spec.Versioning) {
+		drift = append(drift, driftVersioning)
+	}
+
+	if s3Bucket.Spec.Encryption != nil {
+		encryption, err := getBucketEncryption(ctx, s3Client, bucketName)
+		if err != nil {
+			return true, nil, err
+		}
+		if encryption == nil || *encryption != *desiredBucketEncryption(s3Bucket.Spec.Encryption) {
+			drift = append(drift, driftEncryption)
+		}
+	}
+
+	tags, err := getBucketTags(ctx, s3Client, bucketName)
+	if err != nil {
+		return true, nil, err
+	}
+	desiredTags := resourceTags(s3Bucket, providerTags(ctx), s3Bucket.Spec.Tags)
+	if !maps.Equal(tags, mergeBucketTags(tags, s3Bucket.Status.Tags, desiredTags)) {
+		drift = append(drift, driftTags)
+	}
+
+	return true, drift, nil
+}
@@ -18,7 +18,11 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -29,10 +33,25 @@ import (
 	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
 )
 
+const (
+	// ec2ResyncInterval is how often an existing instance is described to detect drift
+	ec2ResyncInterval = 5 * time.Minute
+	// instancePollInterval is how often an instance that is launching, stopping or
+	// terminating is described until it settles
+	instancePollInterval = 15 * time.Second
+
+	instanceStateTerminated = string(ec2types.InstanceStateNameTerminated)
+
+	reasonTerminatedOutOfBand = "TerminatedOutOfBand"
+	reasonResizing            = "Resizing"
+)
+
 // Ec2instanceReconciler reconciles a Ec2instance object
 type Ec2instanceReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// AWSClients creates the AWS clients used to manage the resources
+	AWSClients AWSClientProvider
 }
 
 // +kubebuilder:rbac:groups=compute.cloud.com,resources=ec2instances,verbs=get;list;watch;create;update;patch;delete
@@ -54,7 +73,7 @@ func (r *Ec2instanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	l.Info("=== RECONCILE LOOP STARTED ===", "namespace", req.Namespace, "name", req.Name)
 
 	ec2instance := &computev1.Ec2instance{}
-	if err := r.Get(ctx, req.NamespacedName, ec2instance); err != nil { //this is fetching from cluster and putting into ec2instance variable 
+	if err := r.Get(ctx, req.NamespacedName, ec2instance); err != nil { //this is fetching from cluster and putting into ec2instance variable
 		if errors.IsNotFound(err) {
 			l.Info("Instance Deleted. No need to reconcile.")
 			return ctrl.Result{}, nil
@@ -62,19 +81,44 @@ func (r *Ec2instanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	// Send AWS calls to the account of the ProviderConfig, if the instance names one
+	ctx, providerConfig, providerErr := withProviderConfig(ctx, r.Client, ec2instance.Namespace, ec2instance.Spec.ProviderConfigRef)
+	if providerErr == nil {
+		providerErr = resolveRegion(&ec2instance.Status.Region, ec2instance.Spec.Region, providerConfig)
+	}
+
 	if !ec2instance.DeletionTimestamp.IsZero() {
 		l.Info("Has Deletion timestamp, Instance is being deleted")
 
-		// Only attempt to delete from AWS if an instance was actually created
-		if ec2instance.Status.InstanceID != "" {
-			l.Info("Deleting EC2 instance from AWS", "instanceID", ec2instance.Status.InstanceID)
-			_, err := deleteEc2Instance(ctx, ec2instance)
+		// Only attempt to delete from AWS if an instance was actually created and still exists
+		instanceExists := ec2instance.Status.InstanceID != "" && ec2instance.Status.State != instanceStateTerminated
+
+		switch {
+		case instanceExists && providerErr != nil:
+			// Wait for the account to be reachable again. Never fall back to the operator's own
+			// account, and never drop the finalizer of an instance that is still running.
+			return r.providerConfigFailed(ctx, ec2instance, providerErr)
+		case instanceExists && ec2instance.Spec.DeletionPolicy == computev1.DeletionPolicyRetain:
+			l.Info("Deletion policy is Retain, leaving EC2 instance in AWS", "instanceID", ec2instance.Status.InstanceID)
+		case instanceExists && ec2instance.Spec.DeletionPolicy == computev1.DeletionPolicyStop:
+			if ec2instance.Status.State != string(ec2types.InstanceStateNameStopped) {
+				l.Info("Deletion policy is Stop, stopping EC2 instance", "instanceID", ec2instance.Status.InstanceID)
+				if _, err := stopEc2Instance(ctx, r.AWSClients, ec2instance, false); err != nil {
+					l.Error(err, "Failed to stop EC2 instance in AWS")
+					// Same as termination - don't block deletion on AWS errors
+				}
+			}
+		case instanceExists:
+			terminated, err := r.terminateInstance(ctx, ec2instance)
 			if err != nil {
 				l.Error(err, "Failed to delete EC2 instance from AWS")
 				// Still continue to remove finalizer - don't block deletion on AWS errors
 				// This prevents orphaned Kubernetes resources if AWS is unavailable
+			} else if !terminated {
+				// Termination is in progress - check back later instead of blocking this worker
+				return ctrl.Result{RequeueAfter: instancePollInterval}, nil
 			}
-		} else {
+		default:
 			l.Info("No instance ID found in status, skipping AWS deletion (instance was never created)")
 		}
 
@@ -92,9 +136,13 @@ func (r *Ec2instanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, nil
 	}
 
+	if providerErr != nil {
+		return r.providerConfigFailed(ctx, ec2instance, providerErr)
+	}
+
 	if ec2instance.Status.InstanceID != "" {
-		l.Info("Requested object already exist in K8s. Not creating a new instance", "instance", ec2instance.Status.InstanceID)
-		return ctrl.Result{}, nil
+		l.Info("Requested object already exist in K8s. Not creating a new instance, resyncing status", "instance", ec2instance.Status.InstanceID)
+		return r.syncInstanceStatus(ctx, ec2instance)
 	}
 
 	// Add finalizer if not already present
@@ -116,9 +164,32 @@ func (r *Ec2instanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// Create a new instance
 	l.Info("=== CONTINUING WITH EC2 INSTANCE CREATION IN THE CURRENT RECONCILE ===")
 
-	createdInstanceInfo, err := createEc2Instance(ctx, ec2instance)
+	var createdInstanceInfo *computev1.CreatedInstanceInfo
+	var err error
+	if ec2instance.Spec.InstanceID != "" {
+		createdInstanceInfo, err = adoptEc2Instance(ctx, r.AWSClients, ec2instance)
+	} else {
+		createdInstanceInfo, err = createEc2Instance(ctx, r.AWSClients, ec2instance)
+	}
 	if err != nil {
+		if isInvalidSpec(err) {
+			// Retrying won't fix the spec - report it and wait for the user to edit the resource
+			l.Info("EC2 instance spec rejected", "reason", err.Error())
+			ec2instance.Status.Phase = computev1.InstancePhaseInvalid
+			ec2instance.Status.Message = err.Error()
+			ec2instance.Status.ObservedGeneration = ec2instance.Generation
+			setTerminalError(&ec2instance.Status.Conditions, ec2instance.Generation, reasonInvalidSpec, err.Error())
+			if err := r.Status().Update(ctx, ec2instance); err != nil {
+				l.Error(err, "Failed to update the status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
 		l.Error(err, "Failed to create EC2 instance")
+		setSyncFailed(&ec2instance.Status.Conditions, ec2instance.Generation, reasonCreateFailed, err)
+		if err := r.Status().Update(ctx, ec2instance); err != nil {
+			l.Error(err, "Failed to record the creation failure in status")
+		}
 		// Kubernetes will retry with backoff
 		return ctrl.Result{}, err
 	}
@@ -128,11 +199,14 @@ func (r *Ec2instanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		"state", createdInstanceInfo.State)
 
 	ec2instance.Status.InstanceID = createdInstanceInfo.InstanceId
-	ec2instance.Status.State = createdInstanceInfo.State
-	ec2instance.Status.PrivateIP = createdInstanceInfo.PrivateIP
-	ec2instance.Status.PublicIP = createdInstanceInfo.PublicIP
-	ec2instance.Status.PrivateDNS = createdInstanceInfo.PrivateDNS
-	ec2instance.Status.PublicDNS = createdInstanceInfo.PublicDNS
+	ec2instance.Status.Adopted = ec2instance.Spec.InstanceID != ""
+	ec2instance.Status.Phase = computev1.InstancePhaseLaunching
+	if ec2instance.Status.Adopted {
+		ec2instance.Status.Phase = computev1.InstancePhaseAvailable
+	}
+	applyInstanceInfo(&ec2instance.Status, createdInstanceInfo)
+	ec2instance.Status.Message = ""
+	setInstanceConditions(ec2instance)
 
 	// The Reconcile function must return a ctrl.Result and an error.
 	// Returning ctrl.Result{} with nil error means the reconciliation was successful
@@ -148,8 +222,321 @@ func (r *Ec2instanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	l.Info("=== STATUS UPDATED - Reconcile loop will be triggered again ===")
 
-	// Successfully created and updated status - done
-	return ctrl.Result{}, nil
+	// The instance ID is recorded - poll until AWS reports it running
+	return ctrl.Result{RequeueAfter: instancePollInterval}, nil
+}
+
+// providerConfigFailed records why spec.providerConfigRef or the region it provides can't be
+// used. Invalid references are checked again every ec2ResyncInterval, so creating the missing
+// ProviderConfig or allowing the namespace later is picked up.
+func (r *Ec2instanceReconciler) providerConfigFailed(ctx context.Context, ec2instance *computev1.Ec2instance, err error) (ctrl.Result, error) {
+	l := logf.FromContext(ctx)
+
+	if !isInvalidSpec(err) {
+		l.Error(err, "Failed to resolve the providerConfig")
+		return ctrl.Result{}, err
+	}
+
+	l.Info("EC2 instance providerConfig rejected", "reason", err.Error())
+	ec2instance.Status.Message = err.Error()
+	if ec2instance.Status.InstanceID == "" {
+		ec2instance.Status.Phase = computev1.InstancePhaseInvalid
+	}
+	setTerminalError(&ec2instance.Status.Conditions, ec2instance.Generation, reasonProviderConfigInvalid, err.Error())
+	if err := r.Status().Update(ctx, ec2instance); err != nil {
+		l.Error(err, "Failed to update the status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: ec2ResyncInterval}, nil
+}
+
+// syncInstanceStatus describes an already created instance and copies what AWS reports
+// into status, so that changes made outside the operator (stop, terminate, new public IP)
+// show up on the custom resource. While the instance is in a transitional state such as
+// pending it requeues every instancePollInterval, otherwise every ec2ResyncInterval.
+func (r *Ec2instanceReconciler) syncInstanceStatus(ctx context.Context, ec2instance *computev1.Ec2instance) (ctrl.Result, error) {
+	l := logf.FromContext(ctx)
+
+	if ec2instance.Status.State == instanceStateTerminated {
+		l.Info("Instance is terminated, nothing left to sync", "instanceID", ec2instance.Status.InstanceID)
+		return ctrl.Result{}, nil
+	}
+
+	info, err := describeEc2Instance(ctx, r.AWSClients, ec2instance)
+	if err != nil {
+		l.Error(err, "Failed to describe EC2 instance", "instanceID", ec2instance.Status.InstanceID)
+		setSyncFailed(&ec2instance.Status.Conditions, ec2instance.Generation, reasonSyncFailed, err)
+		if err := r.Status().Update(ctx, ec2instance); err != nil {
+			l.Error(err, "Failed to record the sync failure in status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	original := ec2instance.Status.DeepCopy()
+
+	var actionErr error
+	switch {
+	case info == nil:
+		ec2instance.Status.Phase = computev1.InstancePhaseTerminated
+		ec2instance.Status.State = instanceStateTerminated
+		ec2instance.Status.PublicIP = ""
+		ec2instance.Status.PublicDNS = ""
+		ec2instance.Status.Message = "instance no longer exists in AWS, it was terminated outside of the operator"
+		setTerminalError(&ec2instance.Status.Conditions, ec2instance.Generation, reasonTerminatedOutOfBand, ec2instance.Status.Message)
+	case info.State == instanceStateTerminated || info.State == string(ec2types.InstanceStateNameShuttingDown):
+		applyInstanceInfo(&ec2instance.Status, info)
+		ec2instance.Status.Phase = computev1.InstancePhaseTerminating
+		if info.State == instanceStateTerminated {
+			ec2instance.Status.Phase = computev1.InstancePhaseTerminated
+		}
+		ec2instance.Status.Message = "instance was terminated outside of the operator"
+		setTerminalError(&ec2instance.Status.Conditions, ec2instance.Generation, reasonTerminatedOutOfBand, ec2instance.Status.Message)
+	default:
+		applyInstanceInfo(&ec2instance.Status, info)
+		launching := ec2instance.Status.Phase == computev1.InstancePhaseLaunching && info.State == string(ec2types.InstanceStateNamePending)
+		if !launching && ec2instance.Status.Phase != computev1.InstancePhaseResizing {
+			ec2instance.Status.Phase = computev1.InstancePhaseAvailable
+		}
+		setInstanceConditions(ec2instance)
+		actionErr = r.reconcileInstanceChanges(ctx, ec2instance)
+	}
+	ec2instance.Status.ObservedGeneration = ec2instance.Generation
+
+	if !equality.Semantic.DeepEqual(original, &ec2instance.Status) {
+		l.Info("Instance drifted, updating status",
+			"instanceID", ec2instance.Status.InstanceID,
+			"oldState", original.State,
+			"newState", ec2instance.Status.State)
+		if err := r.Status().Update(ctx, ec2instance); err != nil {
+			l.Error(err, "Failed to update the status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if actionErr != nil {
+		return ctrl.Result{}, actionErr
+	}
+
+	if ec2instance.Status.State == instanceStateTerminated {
+		l.Info("Instance was terminated outside of the operator", "instanceID", ec2instance.Status.InstanceID)
+		return ctrl.Result{}, nil
+	}
+
+	if isTransitionalState(ec2instance.Status.State) || ec2instance.Status.Phase == computev1.InstancePhaseResizing {
+		l.Info("Instance is changing state, polling again shortly",
+			"instanceID", ec2instance.Status.InstanceID,
+			"state", ec2instance.Status.State)
+		return ctrl.Result{RequeueAfter: instancePollInterval}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: ec2ResyncInterval}, nil
+}
+
+// reconcileInstanceChanges applies spec changes to a live instance (instance type, then power
+// state) and records failures in the conditions. It returns the errors worth retrying.
+func (r *Ec2instanceReconciler) reconcileInstanceChanges(ctx context.Context, ec2instance *computev1.Ec2instance) error {
+	l := logf.FromContext(ctx)
+
+	if err := r.reconcileInstanceType(ctx, ec2instance); err != nil {
+		l.Error(err, "Failed to change instance type", "instanceID", ec2instance.Status.InstanceID)
+		setSyncFailed(&ec2instance.Status.Conditions, ec2instance.Generation, "ResizeFailed", err)
+		return err
+	}
+
+	if err := r.reconcilePowerState(ctx, ec2instance); err != nil {
+		l.Error(err, "Failed to change instance power state", "instanceID", ec2instance.Status.InstanceID)
+		if isInvalidSpec(err) {
+			// e.g. hibernating an instance launched without hibernation - retrying won't help
+			setCondition(&ec2instance.Status.Conditions, ec2instance.Generation, computev1.ConditionSynced, false,
+				reasonInvalidSpec, err.Error())
+			return nil
+		}
+		setSyncFailed(&ec2instance.Status.Conditions, ec2instance.Generation, "PowerStateFailed", err)
+		return err
+	}
+	return nil
+}
+
+// reconcileInstanceType applies a changed spec.instanceType to an existing instance. AWS can
+// only change the type of a stopped instance, so a running instance is stopped first (phase
+// Resizing) and modified once it is stopped. Each call does one step and the caller polls
+// until the phase is back to Available; reconcilePowerState then starts the instance again
+// if spec.powerState asks for it. Nothing happens unless the resource carries the
+// AnnotationAllowResize annotation.
+func (r *Ec2instanceReconciler) reconcileInstanceType(ctx context.Context, ec2instance *computev1.Ec2instance) error {
+	l := logf.FromContext(ctx)
+	status := &ec2instance.Status
+	desiredType := ec2instance.Spec.InstanceType
+
+	if status.InstanceType == "" || status.InstanceType == desiredType {
+		if status.Phase == computev1.InstancePhaseResizing && !isTransitionalState(status.State) {
+			l.Info("Instance type change completed", "instanceID", status.InstanceID, "instanceType", desiredType)
+			status.Phase = computev1.InstancePhaseAvailable
+			setInstanceConditions(ec2instance)
+		}
+		return nil
+	}
+
+	if ec2instance.Annotations[computev1.AnnotationAllowResize] != "true" {
+		setCondition(&status.Conditions, ec2instance.Generation, computev1.ConditionSynced, false, "ResizeNotAllowed",
+			fmt.Sprintf("instance type is %s but spec asks for %s; set the %s annotation to \"true\" to allow stopping the instance to change it",
+				status.InstanceType, desiredType, computev1.AnnotationAllowResize))
+		return nil
+	}
+
+	switch status.State {
+	case string(ec2types.InstanceStateNameRunning):
+		l.Info("Stopping instance to change its type", "instanceID", status.InstanceID, "from", status.InstanceType, "to", desiredType)
+		newState, err := stopEc2Instance(ctx, r.AWSClients, ec2instance, false)
+		if err != nil {
+			return err
+		}
+		status.State = newState
+		status.Phase = computev1.InstancePhaseResizing
+	case string(ec2types.InstanceStateNameStopped):
+		if err := modifyEc2InstanceType(ctx, r.AWSClients, ec2instance); err != nil {
+			return err
+		}
+		l.Info("Instance type changed", "instanceID", status.InstanceID, "instanceType", desiredType)
+		status.InstanceType = desiredType
+		status.Phase = computev1.InstancePhaseAvailable
+		setInstanceConditions(ec2instance)
+		return nil
+	default:
+		// pending or stopping - wait for the instance to settle before touching it
+		l.Info("Waiting for instance to settle before changing its type", "instanceID", status.InstanceID, "state", status.State)
+	}
+
+	message := fmt.Sprintf("changing instance type from %s to %s", status.InstanceType, desiredType)
+	setCondition(&status.Conditions, ec2instance.Generation, computev1.ConditionReady, false, reasonResizing, message)
+	setCondition(&status.Conditions, ec2instance.Generation, computev1.ConditionSynced, false, reasonResizing, message)
+	return nil
+}
+
+// reconcilePowerState starts or stops the instance so that it converges on spec.powerState.
+// It only issues the API call; the caller polls until the instance leaves the pending or
+// stopping state and picks up the new public IP and DNS name on the way.
+func (r *Ec2instanceReconciler) reconcilePowerState(ctx context.Context, ec2instance *computev1.Ec2instance) error {
+	l := logf.FromContext(ctx)
+	status := &ec2instance.Status
+
+	if status.Phase == computev1.InstancePhaseResizing || isTransitionalState(status.State) {
+		return nil
+	}
+
+	desiredState := desiredInstanceState(ec2instance)
+	if status.State == desiredState {
+		return nil
+	}
+
+	var newState string
+	var err error
+	switch desiredState {
+	case string(ec2types.InstanceStateNameRunning):
+		l.Info("Starting instance to match desired power state", "instanceID", status.InstanceID, "state", status.State)
+		newState, err = startEc2Instance(ctx, r.AWSClients, ec2instance)
+	case string(ec2types.InstanceStateNameStopped):
+		hibernate := ec2instance.Spec.PowerState == computev1.PowerStateHibernated
+		l.Info("Stopping instance to match desired power state", "instanceID", status.InstanceID, "hibernate", hibernate)
+		newState, err = stopEc2Instance(ctx, r.AWSClients, ec2instance, hibernate)
+	}
+	if err != nil {
+		return err
+	}
+
+	status.State = newState
+	setInstanceConditions(ec2instance)
+	return nil
+}
+
+// desiredInstanceState maps spec.powerState onto the AWS instance state it should end up in
+func desiredInstanceState(ec2instance *computev1.Ec2instance) string {
+	switch ec2instance.Spec.PowerState {
+	case computev1.PowerStateStopped, computev1.PowerStateHibernated:
+		return string(ec2types.InstanceStateNameStopped)
+	}
+	return string(ec2types.InstanceStateNameRunning)
+}
+
+// terminateInstance moves deletion forward by one step per reconcile: the first call asks
+// AWS to terminate the instance and records the Terminating phase, later calls describe the
+// instance until it is gone. It returns true once the instance is terminated.
+func (r *Ec2instanceReconciler) terminateInstance(ctx context.Context, ec2instance *computev1.Ec2instance) (bool, error) {
+	l := logf.FromContext(ctx)
+
+	var state string
+	if ec2instance.Status.Phase != computev1.InstancePhaseTerminating {
+		l.Info("Deleting EC2 instance from AWS", "instanceID", ec2instance.Status.InstanceID)
+		newState, err := deleteEc2Instance(ctx, r.AWSClients, ec2instance)
+		if err != nil {
+			return false, err
+		}
+		state = newState
+	} else {
+		info, err := describeEc2Instance(ctx, r.AWSClients, ec2instance)
+		if err != nil {
+			return false, err
+		}
+		state = instanceStateTerminated
+		if info != nil {
+			state = info.State
+		}
+	}
+
+	if state == instanceStateTerminated {
+		l.Info("EC2 Instance successfully terminated", "instanceID", ec2instance.Status.InstanceID)
+		return true, nil
+	}
+
+	if ec2instance.Status.Phase != computev1.InstancePhaseTerminating || ec2instance.Status.State != state {
+		ec2instance.Status.Phase = computev1.InstancePhaseTerminating
+		ec2instance.Status.State = state
+		setCondition(&ec2instance.Status.Conditions, ec2instance.Generation, computev1.ConditionReady, false,
+			"InstanceTerminating", fmt.Sprintf("instance is %s", state))
+		if err := r.Status().Update(ctx, ec2instance); err != nil {
+			return false, err
+		}
+	}
+
+	l.Info("Waiting for the instance to be terminated", "instanceID", ec2instance.Status.InstanceID, "state", state)
+	return false, nil
+}
+
+// isTransitionalState reports whether AWS is still moving the instance between states
+func isTransitionalState(state string) bool {
+	switch ec2types.InstanceStateName(state) {
+	case ec2types.InstanceStateNamePending, ec2types.InstanceStateNameStopping, ec2types.InstanceStateNameShuttingDown:
+		return true
+	}
+	return false
+}
+
+// setInstanceConditions marks the instance as synced, and as ready once it is in the
+// state spec.powerState asks for
+func setInstanceConditions(ec2instance *computev1.Ec2instance) {
+	ec2instance.Status.ObservedGeneration = ec2instance.Generation
+
+	switch desiredState := desiredInstanceState(ec2instance); {
+	case ec2instance.Status.State != desiredState:
+		setSynced(&ec2instance.Status.Conditions, ec2instance.Generation, false, "InstanceNotReady",
+			fmt.Sprintf("instance is %s, waiting for it to be %s", ec2instance.Status.State, desiredState))
+	case desiredState == string(ec2types.InstanceStateNameStopped):
+		setSynced(&ec2instance.Status.Conditions, ec2instance.Generation, true, "InstanceStopped", "")
+	default:
+		setSynced(&ec2instance.Status.Conditions, ec2instance.Generation, true, "InstanceRunning", "")
+	}
+}
+
+// applyInstanceInfo copies the AWS-reported instance details into status
+func applyInstanceInfo(status *computev1.Ec2instanceStatus, info *computev1.CreatedInstanceInfo) {
+	status.InstanceType = info.InstanceType
+	status.State = info.State
+	status.PrivateIP = info.PrivateIP
+	status.PublicIP = info.PublicIP
+	status.PrivateDNS = info.PrivateDNS
+	status.PublicDNS = info.PublicDNS
+	status.LaunchTime = info.LaunchTime
+	status.Volumes = info.Volumes
 }
 
 // SetupWithManager sets up the controller with the Manager.
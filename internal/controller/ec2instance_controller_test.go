@@ -19,10 +19,15 @@ package controller
 import (
 	"context"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -51,7 +56,11 @@ var _ = Describe("Ec2instance Controller", func() {
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: computev1.Ec2instanceSpec{
+						InstanceType: "t3.micro",
+						AMIId:        "ami-02b8269d5e85954ef",
+						Region:       "ap-south-1",
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
@@ -69,8 +78,9 @@ var _ = Describe("Ec2instance Controller", func() {
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &Ec2instanceReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				AWSClients: newFakeAWS(),
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -81,4 +91,592 @@ var _ = Describe("Ec2instance Controller", func() {
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
 	})
+
+	Context("When managing an instance against the fake AWS backend", func() {
+		const resourceName = "lifecycle-instance"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		var fakeAWS *fakeAWS
+		var controllerReconciler *Ec2instanceReconciler
+
+		reconcileOnce := func() error {
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			return err
+		}
+		getInstance := func() *computev1.Ec2instance {
+			ec2instance := &computev1.Ec2instance{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, ec2instance)).To(Succeed())
+			return ec2instance
+		}
+
+		BeforeEach(func() {
+			fakeAWS = newFakeAWS()
+			controllerReconciler = &Ec2instanceReconciler{
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				AWSClients: fakeAWS,
+			}
+
+			Expect(k8sClient.Create(ctx, &computev1.Ec2instance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: computev1.Ec2instanceSpec{
+					InstanceType:      "t3.micro",
+					AMIId:             "ami-02b8269d5e85954ef",
+					Region:            "ap-south-1",
+					AssociatePublicIP: true,
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			ec2instance := &computev1.Ec2instance{}
+			if err := k8sClient.Get(ctx, typeNamespacedName, ec2instance); errors.IsNotFound(err) {
+				return
+			}
+			controllerutil.RemoveFinalizer(ec2instance, "ec2instance.compute.cloud.com")
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, ec2instance))).To(Succeed())
+		})
+
+		It("should launch, stop and terminate the instance", func() {
+			By("adding the finalizer")
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getInstance().Finalizers).To(ContainElement("ec2instance.compute.cloud.com"))
+
+			By("launching the instance, retrying after a failed RunInstances")
+			fakeAWS.failNext("RunInstances", apiError("InsufficientInstanceCapacity", "no capacity"))
+			Expect(reconcileOnce()).NotTo(Succeed())
+			Expect(findCondition(getInstance().Status.Conditions, computev1.ConditionSynced).Status).To(Equal("False"))
+
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance := getInstance()
+			Expect(ec2instance.Status.InstanceID).NotTo(BeEmpty())
+			Expect(ec2instance.Status.State).To(Equal("pending"))
+			Expect(ec2instance.Status.Phase).To(Equal(computev1.InstancePhaseLaunching))
+			Expect(fakeAWS.callsTo("RunInstances")).To(Equal(2))
+
+			By("waiting for the instance to be running")
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance = getInstance()
+			Expect(ec2instance.Status.State).To(Equal("running"))
+			Expect(ec2instance.Status.Phase).To(Equal(computev1.InstancePhaseAvailable))
+			Expect(ec2instance.Status.PublicIP).NotTo(BeEmpty())
+			Expect(ec2instance.Status.Volumes).NotTo(BeEmpty())
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionReady).Status).To(Equal("True"))
+
+			By("stopping the instance when spec.powerState changes")
+			ec2instance.Spec.PowerState = computev1.PowerStateStopped
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getInstance().Status.State).To(Equal("stopping"))
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance = getInstance()
+			Expect(ec2instance.Status.State).To(Equal("stopped"))
+			Expect(ec2instance.Status.PublicIP).To(BeEmpty())
+			Expect(fakeAWS.callsTo("StopInstances")).To(Equal(1))
+
+			By("terminating the instance when the resource is deleted")
+			instanceID := ec2instance.Status.InstanceID
+			Expect(k8sClient.Delete(ctx, ec2instance)).To(Succeed())
+			Eventually(func() error {
+				if err := reconcileOnce(); err != nil {
+					return err
+				}
+				return k8sClient.Get(ctx, typeNamespacedName, &computev1.Ec2instance{})
+			}).Should(Satisfy(errors.IsNotFound))
+
+			instance, found := fakeAWS.instance(instanceID)
+			Expect(found).To(BeTrue())
+			Expect(instance.State.Name).To(Equal(ec2types.InstanceStateNameTerminated))
+			Expect(fakeAWS.callsTo("TerminateInstances")).To(Equal(1))
+		})
+
+		It("should report an instance terminated outside of the operator", func() {
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			instanceID := getInstance().Status.InstanceID
+
+			ec2Client, err := fakeAWS.EC2(ctx, "ap-south-1")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = ec2Client.TerminateInstances(ctx, &ec2.TerminateInstancesInput{InstanceIds: []string{instanceID}})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance := getInstance()
+			Expect(ec2instance.Status.State).To(Equal("terminated"))
+			Expect(ec2instance.Status.Phase).To(Equal(computev1.InstancePhaseTerminated))
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionReady).Reason).To(Equal(reasonTerminatedOutOfBand))
+		})
+
+		It("should pick up an instance launched with its client token instead of launching another", func() {
+			Expect(reconcileOnce()).To(Succeed())
+
+			// A previous launch went through, but neither its response nor its tags arrived
+			ec2Client, err := fakeAWS.EC2(ctx, "ap-south-1")
+			Expect(err).NotTo(HaveOccurred())
+			launched, err := ec2Client.RunInstances(ctx, &ec2.RunInstancesInput{
+				ImageId:     aws.String("ami-02b8269d5e85954ef"),
+				ClientToken: aws.String(string(getInstance().UID)),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getInstance().Status.InstanceID).To(Equal(aws.ToString(launched.Instances[0].InstanceId)))
+			Expect(fakeAWS.callsTo("RunInstances")).To(Equal(2))
+		})
+
+		It("should pick up an instance tagged with its owner UID instead of launching another", func() {
+			Expect(reconcileOnce()).To(Succeed())
+
+			ec2Client, err := fakeAWS.EC2(ctx, "ap-south-1")
+			Expect(err).NotTo(HaveOccurred())
+			launched, err := ec2Client.RunInstances(ctx, &ec2.RunInstancesInput{
+				ImageId: aws.String("ami-02b8269d5e85954ef"),
+				TagSpecifications: []ec2types.TagSpecification{{
+					ResourceType: ec2types.ResourceTypeInstance,
+					Tags: []ec2types.Tag{{
+						Key:   aws.String(ownerUIDTagKey),
+						Value: aws.String(string(getInstance().UID)),
+					}},
+				}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getInstance().Status.InstanceID).To(Equal(aws.ToString(launched.Instances[0].InstanceId)))
+			Expect(fakeAWS.callsTo("RunInstances")).To(Equal(1))
+		})
+
+		It("should change the instance type only once resizing is allowed", func() {
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance := getInstance()
+			instanceID := ec2instance.Status.InstanceID
+			Expect(ec2instance.Status.State).To(Equal("running"))
+
+			By("refusing to stop the instance without the allow-resize annotation")
+			ec2instance.Spec.InstanceType = "t3.small"
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance = getInstance()
+			Expect(ec2instance.Status.State).To(Equal("running"))
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionSynced).Reason).To(Equal("ResizeNotAllowed"))
+			Expect(fakeAWS.callsTo("StopInstances")).To(BeZero())
+
+			By("stopping, modifying and starting the instance once it is allowed")
+			ec2instance.Annotations = map[string]string{computev1.AnnotationAllowResize: "true"}
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance = getInstance()
+			Expect(ec2instance.Status.Phase).To(Equal(computev1.InstancePhaseResizing))
+			Expect(ec2instance.Status.State).To(Equal("stopping"))
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionReady).Reason).To(Equal(reasonResizing))
+
+			Eventually(func(g Gomega) {
+				g.Expect(reconcileOnce()).To(Succeed())
+				ec2instance := getInstance()
+				g.Expect(ec2instance.Status.Phase).To(Equal(computev1.InstancePhaseAvailable))
+				g.Expect(ec2instance.Status.State).To(Equal("running"))
+			}).Should(Succeed())
+
+			instance, found := fakeAWS.instance(instanceID)
+			Expect(found).To(BeTrue())
+			Expect(instance.InstanceType).To(Equal(ec2types.InstanceType("t3.small")))
+			Expect(getInstance().Status.InstanceType).To(Equal("t3.small"))
+			Expect(fakeAWS.callsTo("ModifyInstanceAttribute")).To(Equal(1))
+			Expect(fakeAWS.callsTo("StartInstances")).To(Equal(1))
+		})
+
+		It("should hibernate an instance launched with hibernation enabled", func() {
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance := getInstance()
+			ec2instance.Spec.Hibernation = true
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance = getInstance()
+			instanceID := ec2instance.Status.InstanceID
+			Expect(ec2instance.Status.State).To(Equal("running"))
+
+			instance, _ := fakeAWS.instance(instanceID)
+			Expect(aws.ToBool(instance.HibernationOptions.Configured)).To(BeTrue())
+
+			ec2instance.Spec.PowerState = computev1.PowerStateHibernated
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance = getInstance()
+			Expect(ec2instance.Status.State).To(Equal("stopped"))
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionSynced).Status).To(Equal("True"))
+
+			instance, _ = fakeAWS.instance(instanceID)
+			Expect(aws.ToString(instance.StateReason.Code)).To(Equal("Client.UserInitiatedHibernate"))
+		})
+
+		It("should reject hibernating an instance launched without hibernation", func() {
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance := getInstance()
+			Expect(ec2instance.Status.State).To(Equal("running"))
+
+			ec2instance.Spec.PowerState = computev1.PowerStateHibernated
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance = getInstance()
+			Expect(ec2instance.Status.State).To(Equal("running"))
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionSynced).Reason).To(Equal(reasonInvalidSpec))
+		})
+
+		It("should leave the instance running when the deletion policy is Retain", func() {
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance := getInstance()
+			instanceID := ec2instance.Status.InstanceID
+
+			ec2instance.Spec.DeletionPolicy = computev1.DeletionPolicyRetain
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, ec2instance)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &computev1.Ec2instance{})).To(Satisfy(errors.IsNotFound))
+
+			instance, found := fakeAWS.instance(instanceID)
+			Expect(found).To(BeTrue())
+			Expect(instance.State.Name).To(Equal(ec2types.InstanceStateNameRunning))
+			Expect(fakeAWS.callsTo("TerminateInstances")).To(BeZero())
+			Expect(fakeAWS.callsTo("StopInstances")).To(BeZero())
+		})
+
+		It("should keep the finalizer while the ProviderConfig of a live instance can't be resolved", func() {
+			providerConfig := &computev1.ProviderConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "lifecycle-account"},
+				Spec:       computev1.ProviderConfigSpec{Region: "ap-south-1"},
+			}
+			Expect(k8sClient.Create(ctx, providerConfig.DeepCopy())).To(Succeed())
+			DeferCleanup(func() {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, providerConfig.DeepCopy()))).To(Succeed())
+			})
+
+			accountName := types.NamespacedName{Name: "account-instance", Namespace: "default"}
+			Expect(k8sClient.Create(ctx, &computev1.Ec2instance{
+				ObjectMeta: metav1.ObjectMeta{Name: accountName.Name, Namespace: accountName.Namespace},
+				Spec: computev1.Ec2instanceSpec{
+					InstanceType:      "t3.micro",
+					AMIId:             "ami-02b8269d5e85954ef",
+					ProviderConfigRef: providerConfig.Name,
+				},
+			})).To(Succeed())
+			DeferCleanup(func() {
+				ec2instance := &computev1.Ec2instance{}
+				if err := k8sClient.Get(ctx, accountName, ec2instance); errors.IsNotFound(err) {
+					return
+				}
+				controllerutil.RemoveFinalizer(ec2instance, "ec2instance.compute.cloud.com")
+				Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, ec2instance))).To(Succeed())
+			})
+
+			reconcileAccountInstance := func() error {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: accountName})
+				return err
+			}
+			ec2instance := &computev1.Ec2instance{}
+			Eventually(func(g Gomega) {
+				g.Expect(reconcileAccountInstance()).To(Succeed())
+				g.Expect(k8sClient.Get(ctx, accountName, ec2instance)).To(Succeed())
+				g.Expect(ec2instance.Status.State).To(Equal("running"))
+			}).Should(Succeed())
+			instanceID := ec2instance.Status.InstanceID
+			Expect(ec2instance.Spec.Region).To(BeEmpty())
+			Expect(ec2instance.Status.Region).To(Equal("ap-south-1"))
+
+			By("waiting instead of orphaning the instance while the ProviderConfig is missing")
+			Expect(k8sClient.Delete(ctx, providerConfig.DeepCopy())).To(Succeed())
+			Expect(k8sClient.Delete(ctx, ec2instance)).To(Succeed())
+			Expect(reconcileAccountInstance()).To(Succeed())
+
+			Expect(k8sClient.Get(ctx, accountName, ec2instance)).To(Succeed())
+			Expect(ec2instance.Finalizers).To(ContainElement("ec2instance.compute.cloud.com"))
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionReady).Reason).To(Equal(reasonProviderConfigInvalid))
+			instance, _ := fakeAWS.instance(instanceID)
+			Expect(instance.State.Name).To(Equal(ec2types.InstanceStateNameRunning))
+			Expect(fakeAWS.callsTo("TerminateInstances")).To(BeZero())
+
+			By("terminating the instance once the ProviderConfig is back")
+			Expect(k8sClient.Create(ctx, providerConfig.DeepCopy())).To(Succeed())
+			Eventually(func() error {
+				if err := reconcileAccountInstance(); err != nil {
+					return err
+				}
+				return k8sClient.Get(ctx, accountName, &computev1.Ec2instance{})
+			}).Should(Satisfy(errors.IsNotFound))
+
+			instance, _ = fakeAWS.instance(instanceID)
+			Expect(instance.State.Name).To(Equal(ec2types.InstanceStateNameTerminated))
+		})
+
+		It("should reject a subnet in another availability zone without launching anything", func() {
+			fakeAWS.addSubnet("subnet-0b1", "ap-south-1b")
+			ec2instance := getInstance()
+			ec2instance.Spec.Subnet = "subnet-0b1"
+			ec2instance.Spec.AvailabilityZone = "ap-south-1a"
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance = getInstance()
+			Expect(ec2instance.Status.Phase).To(Equal(computev1.InstancePhaseInvalid))
+			Expect(ec2instance.Status.InstanceID).To(BeEmpty())
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionSynced).Reason).To(Equal(reasonInvalidSpec))
+			Expect(fakeAWS.callsTo("RunInstances")).To(BeZero())
+		})
+
+		It("should declare the network interface when a public IP is requested without a subnet", func() {
+			ec2instance := getInstance()
+			ec2instance.Spec.SecurityGroups = []string{"sg-0a1"}
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			launch, found := fakeAWS.launchRequest(getInstance().Status.InstanceID)
+			Expect(found).To(BeTrue())
+			Expect(launch.NetworkInterfaces).To(HaveLen(1))
+			networkInterface := launch.NetworkInterfaces[0]
+			Expect(aws.ToInt32(networkInterface.DeviceIndex)).To(BeZero())
+			Expect(aws.ToBool(networkInterface.AssociatePublicIpAddress)).To(BeTrue())
+			Expect(networkInterface.SubnetId).To(BeNil())
+			Expect(networkInterface.Groups).To(Equal([]string{"sg-0a1"}))
+			Expect(launch.SubnetId).To(BeNil())
+			Expect(launch.SecurityGroupIds).To(BeEmpty())
+		})
+
+		It("should set the subnet on the network interface only", func() {
+			fakeAWS.addSubnet("subnet-0a1", "ap-south-1a")
+			ec2instance := getInstance()
+			ec2instance.Spec.Subnet = "subnet-0a1"
+			ec2instance.Spec.AvailabilityZone = "ap-south-1a"
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			instanceID := getInstance().Status.InstanceID
+			launch, found := fakeAWS.launchRequest(instanceID)
+			Expect(found).To(BeTrue())
+			Expect(launch.NetworkInterfaces).To(HaveLen(1))
+			Expect(aws.ToString(launch.NetworkInterfaces[0].SubnetId)).To(Equal("subnet-0a1"))
+			Expect(launch.SubnetId).To(BeNil())
+
+			instance, _ := fakeAWS.instance(instanceID)
+			Expect(aws.ToString(instance.SubnetId)).To(Equal("subnet-0a1"))
+			Expect(aws.ToString(instance.Placement.AvailabilityZone)).To(Equal("ap-south-1a"))
+		})
+
+		It("should report Ready, Synced and Error for the generation it reconciled", func() {
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+
+			ec2instance := getInstance()
+			Expect(ec2instance.Status.State).To(Equal("running"))
+			Expect(ec2instance.Status.ObservedGeneration).To(Equal(ec2instance.Generation))
+			for condType, status := range map[string]string{
+				computev1.ConditionReady:  "True",
+				computev1.ConditionSynced: "True",
+				computev1.ConditionError:  "False",
+			} {
+				condition := findCondition(ec2instance.Status.Conditions, condType)
+				Expect(condition.Status).To(Equal(status), condType)
+				Expect(condition.ObservedGeneration).To(Equal(ec2instance.Generation), condType)
+			}
+
+			By("keeping Ready and leaving Error False when a retry may fix the failure")
+			fakeAWS.failNext("DescribeInstances", apiError("RequestLimitExceeded", "slow down"))
+			Expect(reconcileOnce()).NotTo(Succeed())
+			ec2instance = getInstance()
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionReady).Status).To(Equal("True"))
+			synced := findCondition(ec2instance.Status.Conditions, computev1.ConditionSynced)
+			Expect(synced.Status).To(Equal("False"))
+			Expect(synced.Reason).To(Equal(reasonSyncFailed))
+			Expect(synced.Message).To(ContainSubstring("RequestLimitExceeded"))
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionError).Status).To(Equal("False"))
+
+			By("recovering on the next successful sync")
+			Expect(reconcileOnce()).To(Succeed())
+			ec2instance = getInstance()
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionSynced).Status).To(Equal("True"))
+		})
+
+		It("should adopt an untagged instance and tag it and its volumes", func() {
+			ec2Client, err := fakeAWS.EC2(ctx, "ap-south-1")
+			Expect(err).NotTo(HaveOccurred())
+			launched, err := ec2Client.RunInstances(ctx, &ec2.RunInstancesInput{
+				ImageId: aws.String("ami-02b8269d5e85954ef"),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			instanceID := aws.ToString(launched.Instances[0].InstanceId)
+
+			ec2instance := getInstance()
+			ec2instance.Spec.InstanceID = instanceID
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+
+			ec2instance = getInstance()
+			Expect(ec2instance.Status.Adopted).To(BeTrue())
+			Expect(ec2instance.Status.InstanceID).To(Equal(instanceID))
+			Expect(fakeAWS.callsTo("RunInstances")).To(Equal(1))
+
+			ownerTag := ec2types.Tag{Key: aws.String(ownerUIDTagKey), Value: aws.String(string(ec2instance.UID))}
+			instance, _ := fakeAWS.instance(instanceID)
+			Expect(instance.Tags).To(ContainElement(ownerTag))
+			Expect(instance.BlockDeviceMappings).NotTo(BeEmpty())
+			for _, mapping := range instance.BlockDeviceMappings {
+				volumeTags, found := fakeAWS.volumeTags(aws.ToString(mapping.Ebs.VolumeId))
+				Expect(found).To(BeTrue())
+				Expect(volumeTags).To(ContainElement(ownerTag))
+			}
+		})
+
+		It("should refuse to adopt an instance that belongs to another resource", func() {
+			ec2Client, err := fakeAWS.EC2(ctx, "ap-south-1")
+			Expect(err).NotTo(HaveOccurred())
+			launched, err := ec2Client.RunInstances(ctx, &ec2.RunInstancesInput{
+				ImageId: aws.String("ami-02b8269d5e85954ef"),
+				TagSpecifications: []ec2types.TagSpecification{{
+					ResourceType: ec2types.ResourceTypeInstance,
+					Tags:         []ec2types.Tag{{Key: aws.String(ownerUIDTagKey), Value: aws.String("another-uid")}},
+				}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			instanceID := aws.ToString(launched.Instances[0].InstanceId)
+
+			ec2instance := getInstance()
+			ec2instance.Spec.InstanceID = instanceID
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+
+			ec2instance = getInstance()
+			Expect(ec2instance.Status.InstanceID).To(BeEmpty())
+			Expect(ec2instance.Status.Phase).To(Equal(computev1.InstancePhaseInvalid))
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionError).Reason).To(Equal(reasonInvalidSpec))
+			Expect(ec2instance.Status.Message).To(ContainSubstring("another-uid"))
+			Expect(fakeAWS.callsTo("CreateTags")).To(BeZero())
+		})
+
+		It("should report an instance to adopt that does not exist", func() {
+			ec2instance := getInstance()
+			ec2instance.Spec.InstanceID = "i-0123456789abcdef0"
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+
+			ec2instance = getInstance()
+			Expect(ec2instance.Status.InstanceID).To(BeEmpty())
+			Expect(ec2instance.Status.Phase).To(Equal(computev1.InstancePhaseInvalid))
+			Expect(findCondition(ec2instance.Status.Conditions, computev1.ConditionError).Reason).To(Equal(reasonInvalidSpec))
+			Expect(ec2instance.Status.Message).To(ContainSubstring("not found"))
+			Expect(fakeAWS.callsTo("RunInstances")).To(BeZero())
+		})
+
+		It("should tag the instance and its volumes once per key, keeping the ownership tags", func() {
+			ec2instance := getInstance()
+			ec2instance.Spec.Tags = map[string]string{
+				nameTagKey:     "spoofed-name",
+				ownerUIDTagKey: "spoofed-uid",
+				"team":         "platform",
+			}
+			Expect(k8sClient.Update(ctx, ec2instance)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+
+			ec2instance = getInstance()
+			Expect(ec2instance.Status.InstanceID).NotTo(BeEmpty())
+			instance, found := fakeAWS.instance(ec2instance.Status.InstanceID)
+			Expect(found).To(BeTrue())
+
+			expected := []ec2types.Tag{
+				{Key: aws.String(managedByTagKey), Value: aws.String(managedByTagValue)},
+				{Key: aws.String(nameTagKey), Value: aws.String(ec2instance.Name)},
+				{Key: aws.String(namespaceTagKey), Value: aws.String(ec2instance.Namespace)},
+				{Key: aws.String(ownerUIDTagKey), Value: aws.String(string(ec2instance.UID))},
+				{Key: aws.String("team"), Value: aws.String("platform")},
+			}
+			Expect(instance.Tags).To(ConsistOf(expected))
+			Expect(instance.BlockDeviceMappings).NotTo(BeEmpty())
+			for _, mapping := range instance.BlockDeviceMappings {
+				volumeTags, found := fakeAWS.volumeTags(aws.ToString(mapping.Ebs.VolumeId))
+				Expect(found).To(BeTrue())
+				Expect(volumeTags).To(ConsistOf(expected))
+			}
+		})
+	})
+
+	Context("When building block device mappings", func() {
+		It("should default additional volume device names and keep the root disk untouched", func() {
+			ec2instance := &computev1.Ec2instance{
+				Spec: computev1.Ec2instanceSpec{
+					Storage: computev1.StorageConfig{
+						AdditionalVolumes: []computev1.VolumeConfig{
+							{Size: 100, Type: "gp3", Encrypted: true},
+							{Size: 50, DeviceName: "/dev/sdz"},
+						},
+					},
+				},
+			}
+
+			// No root override means the AMI is never described, so no client is needed
+			mappings, err := buildBlockDeviceMappings(ctx, nil, ec2instance)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mappings).To(HaveLen(2))
+			Expect(*mappings[0].DeviceName).To(Equal("/dev/sdf"))
+			Expect(*mappings[0].Ebs.VolumeSize).To(Equal(int32(100)))
+			Expect(*mappings[0].Ebs.Encrypted).To(BeTrue())
+			Expect(*mappings[1].DeviceName).To(Equal("/dev/sdz"))
+			Expect(mappings[1].Ebs.Encrypted).To(BeNil())
+		})
+
+		It("should skip device names other volumes use and reject specs that run out of names", func() {
+			ec2instance := &computev1.Ec2instance{
+				Spec: computev1.Ec2instanceSpec{
+					Storage: computev1.StorageConfig{
+						AdditionalVolumes: []computev1.VolumeConfig{
+							{Size: 10},
+							{Size: 20, DeviceName: "/dev/sdf"},
+							{Size: 30},
+						},
+					},
+				},
+			}
+
+			mappings, err := buildBlockDeviceMappings(ctx, nil, ec2instance)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*mappings[0].DeviceName).To(Equal("/dev/sdg"))
+			Expect(*mappings[1].DeviceName).To(Equal("/dev/sdf"))
+			Expect(*mappings[2].DeviceName).To(Equal("/dev/sdh"))
+
+			// /dev/sdf through /dev/sdz leaves room for 21 volumes
+			ec2instance.Spec.Storage.AdditionalVolumes = make([]computev1.VolumeConfig, 22)
+			for i := range ec2instance.Spec.Storage.AdditionalVolumes {
+				ec2instance.Spec.Storage.AdditionalVolumes[i].Size = 10
+			}
+			_, err = buildBlockDeviceMappings(ctx, nil, ec2instance)
+			Expect(isInvalidSpec(err)).To(BeTrue())
+
+			ec2instance.Spec.Storage.AdditionalVolumes = []computev1.VolumeConfig{{Type: "gp3"}}
+			_, err = buildBlockDeviceMappings(ctx, nil, ec2instance)
+			Expect(isInvalidSpec(err)).To(BeTrue())
+		})
+	})
 })
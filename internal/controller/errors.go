@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/smithy-go"
+)
+
+// invalidSpecError is returned when the custom resource spec cannot be satisfied as written.
+// Retrying will not help, so the reconciler reports it in status and waits for a spec change.
+type invalidSpecError struct {
+	msg string
+}
+
+func (e *invalidSpecError) Error() string {
+	return e.msg
+}
+
+// newInvalidSpecError formats an invalidSpecError
+func newInvalidSpecError(format string, args ...any) error {
+	return &invalidSpecError{msg: fmt.Sprintf(format, args...)}
+}
+
+// isInvalidSpec reports whether err (or any error it wraps) is an invalidSpecError
+func isInvalidSpec(err error) bool {
+	var specErr *invalidSpecError
+	return errors.As(err, &specErr)
+}
+
+// awsErrorCode returns the AWS API error code carried by err, or "" if there is none
+func awsErrorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
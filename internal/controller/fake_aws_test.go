@@ -0,0 +1,1061 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeAWS is an in-memory EC2 and S3 backend for the controller tests. It keeps instances
+// and buckets across calls, moves instances through their states the way EC2 does, records
+// every call and can be told to fail the next call of an operation.
+//
+// Instances settle one step per DescribeInstances call: pending becomes running, stopping
+// becomes stopped and shutting-down becomes terminated. Terminated instances stay visible,
+// as they do in AWS.
+type fakeAWS struct {
+	mu sync.Mutex
+
+	instances map[string]*fakeInstance
+	buckets   map[string]*fakeBucket
+	// subnets maps subnet IDs to their availability zone
+	subnets map[string]string
+	// volumes maps the IDs of the volumes of all instances to their tags
+	volumes map[string][]ec2types.Tag
+	// lingering counts the HeadBucket calls that still find a bucket once it is deleted
+	lingering map[string]int
+
+	nextID   int
+	calls    []string
+	failures map[string][]error
+}
+
+// fakeInstance is an EC2 instance and the launch details EC2 keeps to itself
+type fakeInstance struct {
+	region       string
+	clientToken  string
+	wantPublicIP bool
+	starts       int
+	launch       ec2.RunInstancesInput
+	instance     ec2types.Instance
+}
+
+// fakeBucket is an S3 bucket and its configuration. Buckets are global, like S3 bucket names.
+type fakeBucket struct {
+	region            string
+	objectOwnership   s3types.ObjectOwnership
+	publicAccessBlock *s3types.PublicAccessBlockConfiguration
+	acl               s3types.BucketCannedACL
+	tags              []s3types.Tag
+	versioning        s3types.BucketVersioningStatus
+	policy            *string
+	website           *s3types.WebsiteConfiguration
+	lifecycle         []s3types.LifecycleRule
+	cors              []s3types.CORSRule
+	encryption        *s3types.ServerSideEncryptionConfiguration
+	objects           []fakeObjectVersion
+}
+
+// fakeObjectVersion is one version or delete marker stored in a bucket
+type fakeObjectVersion struct {
+	key          string
+	versionID    string
+	deleteMarker bool
+}
+
+var _ AWSClientProvider = &fakeAWS{}
+
+func newFakeAWS() *fakeAWS {
+	return &fakeAWS{
+		instances: map[string]*fakeInstance{},
+		buckets:   map[string]*fakeBucket{},
+		subnets:   map[string]string{},
+		volumes:   map[string][]ec2types.Tag{},
+		lingering: map[string]int{},
+		failures:  map[string][]error{},
+	}
+}
+
+// EC2 implements AWSClientProvider with a client that only sees instances in region
+func (f *fakeAWS) EC2(_ context.Context, region string) (EC2API, error) {
+	return &fakeEC2{backend: f, region: region}, nil
+}
+
+// S3 implements AWSClientProvider
+func (f *fakeAWS) S3(_ context.Context, region string) (S3API, error) {
+	return &fakeS3{backend: f, region: region}, nil
+}
+
+// failNext makes the next call of operation, e.g. "RunInstances", return err instead
+func (f *fakeAWS) failNext(operation string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[operation] = append(f.failures[operation], err)
+}
+
+// callsTo returns how many times operation was called, failed calls included
+func (f *fakeAWS) callsTo(operation string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, call := range f.calls {
+		if call == operation {
+			count++
+		}
+	}
+	return count
+}
+
+// addSubnet makes a subnet known to DescribeSubnets
+func (f *fakeAWS) addSubnet(subnetID, availabilityZone string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subnets[subnetID] = availabilityZone
+}
+
+// putObject stores a new version of key, or replaces it when versioning is not enabled
+func (f *fakeAWS) putObject(bucketName, key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	bucket := f.buckets[bucketName]
+	if bucket.versioning != s3types.BucketVersioningStatusEnabled {
+		bucket.objects = slices.DeleteFunc(bucket.objects, func(o fakeObjectVersion) bool { return o.key == key })
+	}
+	bucket.objects = append(bucket.objects, fakeObjectVersion{key: key, versionID: f.newID("v")})
+}
+
+// lingerAfterDelete makes the next calls HeadBucket calls find bucketName once it is deleted,
+// as S3 may while the deletion propagates
+func (f *fakeAWS) lingerAfterDelete(bucketName string, calls int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lingering[bucketName] = calls
+}
+
+// instance returns a copy of the instance as EC2 currently has it
+func (f *fakeAWS) instance(instanceID string) (ec2types.Instance, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fake, ok := f.instances[instanceID]
+	if !ok {
+		return ec2types.Instance{}, false
+	}
+	return fake.instance, true
+}
+
+// volumeTags returns the tags of a volume attached to one of the instances
+func (f *fakeAWS) volumeTags(volumeID string) ([]ec2types.Tag, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tags, ok := f.volumes[volumeID]
+	return slices.Clone(tags), ok
+}
+
+// launchRequest returns the RunInstances request that launched the instance
+func (f *fakeAWS) launchRequest(instanceID string) (ec2.RunInstancesInput, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fake, ok := f.instances[instanceID]
+	if !ok {
+		return ec2.RunInstancesInput{}, false
+	}
+	return fake.launch, true
+}
+
+// bucket returns the bucket named bucketName, if it exists
+func (f *fakeAWS) bucket(bucketName string) (*fakeBucket, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	bucket, ok := f.buckets[bucketName]
+	return bucket, ok
+}
+
+// record notes a call and returns the error queued for it, if any. Callers hold f.mu.
+func (f *fakeAWS) record(operation string) error {
+	f.calls = append(f.calls, operation)
+	if queued := f.failures[operation]; len(queued) > 0 {
+		f.failures[operation] = queued[1:]
+		return queued[0]
+	}
+	return nil
+}
+
+// newID returns a unique AWS style ID with prefix. Callers hold f.mu.
+func (f *fakeAWS) newID(prefix string) string {
+	f.nextID++
+	return fmt.Sprintf("%s-%017x", prefix, f.nextID)
+}
+
+// apiError builds an error with an AWS error code, as the SDK returns them
+func apiError(code, format string, args ...any) error {
+	return &smithy.GenericAPIError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// fakeEC2 is the EC2 client of fakeAWS for one region
+type fakeEC2 struct {
+	backend *fakeAWS
+	region  string
+}
+
+var _ EC2API = &fakeEC2{}
+
+func (c *fakeEC2) RunInstances(_ context.Context, params *ec2.RunInstancesInput, _ ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+	f := c.backend
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("RunInstances"); err != nil {
+		return nil, err
+	}
+
+	// The client token makes launches idempotent
+	if token := aws.ToString(params.ClientToken); token != "" {
+		for _, fake := range f.instances {
+			if fake.region == c.region && fake.clientToken == token {
+				return &ec2.RunInstancesOutput{Instances: []ec2types.Instance{fake.instance}}, nil
+			}
+		}
+	}
+	if aws.ToString(params.ImageId) == "" {
+		return nil, apiError("MissingParameter", "The request must contain the parameter ImageId")
+	}
+	if len(params.NetworkInterfaces) > 0 && (params.SubnetId != nil || len(params.SecurityGroupIds) > 0) {
+		return nil, apiError("InvalidParameterCombination", "Network interfaces and an instance-level subnet ID or security groups may not be specified on the same request")
+	}
+
+	instanceID := f.newID("i")
+	availabilityZone := c.region + "a"
+	if params.Placement != nil && params.Placement.AvailabilityZone != nil {
+		availabilityZone = *params.Placement.AvailabilityZone
+	}
+	privateIP := fmt.Sprintf("10.0.%d.%d", f.nextID/250, f.nextID%250+4)
+
+	fake := &fakeInstance{
+		region:      c.region,
+		clientToken: aws.ToString(params.ClientToken),
+		launch:      *params,
+		instance: ec2types.Instance{
+			InstanceId:       aws.String(instanceID),
+			ImageId:          params.ImageId,
+			InstanceType:     params.InstanceType,
+			KeyName:          params.KeyName,
+			LaunchTime:       aws.Time(time.Now().UTC().Truncate(time.Second)),
+			Placement:        &ec2types.Placement{AvailabilityZone: aws.String(availabilityZone)},
+			PrivateIpAddress: aws.String(privateIP),
+			PrivateDnsName:   aws.String(fmt.Sprintf("ip-%s.%s.compute.internal", strings.ReplaceAll(privateIP, ".", "-"), c.region)),
+			State:            &ec2types.InstanceState{Name: ec2types.InstanceStateNamePending},
+			SubnetId:         params.SubnetId,
+		},
+	}
+	if len(params.NetworkInterfaces) > 0 {
+		fake.wantPublicIP = aws.ToBool(params.NetworkInterfaces[0].AssociatePublicIpAddress)
+		fake.instance.SubnetId = params.NetworkInterfaces[0].SubnetId
+	}
+	if params.HibernationOptions != nil {
+		fake.instance.HibernationOptions = &ec2types.HibernationOptions{Configured: params.HibernationOptions.Configured}
+	}
+	var volumeTags []ec2types.Tag
+	for _, spec := range params.TagSpecifications {
+		switch spec.ResourceType {
+		case ec2types.ResourceTypeInstance:
+			fake.instance.Tags = append(fake.instance.Tags, spec.Tags...)
+		case ec2types.ResourceTypeVolume:
+			volumeTags = append(volumeTags, spec.Tags...)
+		}
+	}
+
+	// Every AMI has a root volume, the mappings may override it or add more
+	devices := []string{"/dev/xvda"}
+	for _, mapping := range params.BlockDeviceMappings {
+		if device := aws.ToString(mapping.DeviceName); !slices.Contains(devices, device) {
+			devices = append(devices, device)
+		}
+	}
+	for _, device := range devices {
+		volumeID := f.newID("vol")
+		f.volumes[volumeID] = slices.Clone(volumeTags)
+		fake.instance.BlockDeviceMappings = append(fake.instance.BlockDeviceMappings, ec2types.InstanceBlockDeviceMapping{
+			DeviceName: aws.String(device),
+			Ebs:        &ec2types.EbsInstanceBlockDevice{VolumeId: aws.String(volumeID)},
+		})
+	}
+
+	f.instances[instanceID] = fake
+	return &ec2.RunInstancesOutput{Instances: []ec2types.Instance{fake.instance}}, nil
+}
+
+func (c *fakeEC2) DescribeInstances(_ context.Context, params *ec2.DescribeInstancesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	f := c.backend
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("DescribeInstances"); err != nil {
+		return nil, err
+	}
+
+	var matched []*fakeInstance
+	if len(params.InstanceIds) > 0 {
+		for _, instanceID := range params.InstanceIds {
+			fake, err := c.lookup(instanceID)
+			if err != nil {
+				return nil, err
+			}
+			matched = append(matched, fake)
+		}
+	} else {
+		for _, fake := range f.instances {
+			if fake.region == c.region {
+				matched = append(matched, fake)
+			}
+		}
+	}
+
+	output := &ec2.DescribeInstancesOutput{}
+	for _, fake := range matched {
+		fake.settle()
+		ok, err := matchesFilters(fake.instance, params.Filters)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			output.Reservations = append(output.Reservations, ec2types.Reservation{
+				Instances: []ec2types.Instance{fake.instance},
+			})
+		}
+	}
+	return output, nil
+}
+
+func (c *fakeEC2) StartInstances(_ context.Context, params *ec2.StartInstancesInput, _ ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	f := c.backend
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("StartInstances"); err != nil {
+		return nil, err
+	}
+
+	output := &ec2.StartInstancesOutput{}
+	for _, instanceID := range params.InstanceIds {
+		fake, err := c.lookup(instanceID)
+		if err != nil {
+			return nil, err
+		}
+		previous := fake.instance.State.Name
+		switch previous {
+		case ec2types.InstanceStateNameStopped:
+			fake.setState(ec2types.InstanceStateNamePending)
+			fake.instance.StateReason = nil
+		case ec2types.InstanceStateNamePending, ec2types.InstanceStateNameRunning:
+		default:
+			return nil, apiError("IncorrectInstanceState", "The instance '%s' is not in a state from which it can be started.", instanceID)
+		}
+		output.StartingInstances = append(output.StartingInstances, fake.stateChange(previous))
+	}
+	return output, nil
+}
+
+func (c *fakeEC2) StopInstances(_ context.Context, params *ec2.StopInstancesInput, _ ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
+	f := c.backend
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("StopInstances"); err != nil {
+		return nil, err
+	}
+
+	output := &ec2.StopInstancesOutput{}
+	for _, instanceID := range params.InstanceIds {
+		fake, err := c.lookup(instanceID)
+		if err != nil {
+			return nil, err
+		}
+		if aws.ToBool(params.Hibernate) && (fake.instance.HibernationOptions == nil || !aws.ToBool(fake.instance.HibernationOptions.Configured)) {
+			return nil, apiError("UnsupportedHibernationConfiguration", "The instance '%s' does not have hibernation configured.", instanceID)
+		}
+		previous := fake.instance.State.Name
+		switch previous {
+		case ec2types.InstanceStateNamePending, ec2types.InstanceStateNameRunning:
+			fake.setState(ec2types.InstanceStateNameStopping)
+			fake.instance.StateReason = &ec2types.StateReason{Code: aws.String("Client.UserInitiatedShutdown")}
+			if aws.ToBool(params.Hibernate) {
+				fake.instance.StateReason = &ec2types.StateReason{Code: aws.String("Client.UserInitiatedHibernate")}
+			}
+		case ec2types.InstanceStateNameStopping, ec2types.InstanceStateNameStopped:
+		default:
+			return nil, apiError("IncorrectInstanceState", "The instance '%s' is not in a state from which it can be stopped.", instanceID)
+		}
+		output.StoppingInstances = append(output.StoppingInstances, fake.stateChange(previous))
+	}
+	return output, nil
+}
+
+func (c *fakeEC2) TerminateInstances(_ context.Context, params *ec2.TerminateInstancesInput, _ ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
+	f := c.backend
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("TerminateInstances"); err != nil {
+		return nil, err
+	}
+
+	output := &ec2.TerminateInstancesOutput{}
+	for _, instanceID := range params.InstanceIds {
+		fake, err := c.lookup(instanceID)
+		if err != nil {
+			return nil, err
+		}
+		previous := fake.instance.State.Name
+		if previous != ec2types.InstanceStateNameTerminated {
+			fake.setState(ec2types.InstanceStateNameShuttingDown)
+		}
+		output.TerminatingInstances = append(output.TerminatingInstances, fake.stateChange(previous))
+	}
+	return output, nil
+}
+
+func (c *fakeEC2) ModifyInstanceAttribute(_ context.Context, params *ec2.ModifyInstanceAttributeInput, _ ...func(*ec2.Options)) (*ec2.ModifyInstanceAttributeOutput, error) {
+	f := c.backend
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("ModifyInstanceAttribute"); err != nil {
+		return nil, err
+	}
+
+	fake, err := c.lookup(aws.ToString(params.InstanceId))
+	if err != nil {
+		return nil, err
+	}
+	if params.InstanceType != nil {
+		if fake.instance.State.Name != ec2types.InstanceStateNameStopped {
+			return nil, apiError("IncorrectInstanceState", "The instance '%s' is not in the 'stopped' state.", aws.ToString(params.InstanceId))
+		}
+		fake.instance.InstanceType = ec2types.InstanceType(aws.ToString(params.InstanceType.Value))
+	}
+	return &ec2.ModifyInstanceAttributeOutput{}, nil
+}
+
+func (c *fakeEC2) CreateTags(_ context.Context, params *ec2.CreateTagsInput, _ ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+	f := c.backend
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("CreateTags"); err != nil {
+		return nil, err
+	}
+
+	for _, resourceID := range params.Resources {
+		if strings.HasPrefix(resourceID, "vol-") {
+			tags, ok := f.volumes[resourceID]
+			if !ok {
+				return nil, apiError("InvalidVolume.NotFound", "The volume '%s' does not exist.", resourceID)
+			}
+			f.volumes[resourceID] = mergeTags(tags, params.Tags)
+			continue
+		}
+		fake, err := c.lookup(resourceID)
+		if err != nil {
+			return nil, err
+		}
+		fake.instance.Tags = mergeTags(fake.instance.Tags, params.Tags)
+	}
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+// mergeTags adds tags to existing, replacing the values of keys it already has
+func mergeTags(existing, tags []ec2types.Tag) []ec2types.Tag {
+	for _, tag := range tags {
+		existing = slices.DeleteFunc(existing, func(t ec2types.Tag) bool {
+			return aws.ToString(t.Key) == aws.ToString(tag.Key)
+		})
+		existing = append(existing, tag)
+	}
+	return existing
+}
+
+func (c *fakeEC2) DescribeImages(_ context.Context, params *ec2.DescribeImagesInput, _ ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error) {
+	f := c.backend
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("DescribeImages"); err != nil {
+		return nil, err
+	}
+
+	// Every AMI exists and boots from /dev/xvda
+	output := &ec2.DescribeImagesOutput{}
+	for _, imageID := range params.ImageIds {
+		output.Images = append(output.Images, ec2types.Image{
+			ImageId:        aws.String(imageID),
+			RootDeviceName: aws.String("/dev/xvda"),
+		})
+	}
+	return output, nil
+}
+
+func (c *fakeEC2) DescribeSubnets(_ context.Context, params *ec2.DescribeSubnetsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	f := c.backend
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("DescribeSubnets"); err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeSubnetsOutput{}
+	for _, subnetID := range params.SubnetIds {
+		availabilityZone, ok := f.subnets[subnetID]
+		if !ok {
+			return nil, apiError("InvalidSubnetID.NotFound", "The subnet ID '%s' does not exist", subnetID)
+		}
+		output.Subnets = append(output.Subnets, ec2types.Subnet{
+			SubnetId:         aws.String(subnetID),
+			AvailabilityZone: aws.String(availabilityZone),
+		})
+	}
+	return output, nil
+}
+
+// lookup finds an instance in the client's region. Callers hold the backend lock.
+func (c *fakeEC2) lookup(instanceID string) (*fakeInstance, error) {
+	if !strings.HasPrefix(instanceID, "i-") {
+		return nil, apiError("InvalidInstanceID.Malformed", "Invalid id: \"%s\"", instanceID)
+	}
+	fake, ok := c.backend.instances[instanceID]
+	if !ok || fake.region != c.region {
+		return nil, apiError("InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", instanceID)
+	}
+	return fake, nil
+}
+
+// settle moves a transitional instance one step towards its final state
+func (i *fakeInstance) settle() {
+	switch i.instance.State.Name {
+	case ec2types.InstanceStateNamePending:
+		i.setState(ec2types.InstanceStateNameRunning)
+		i.starts++
+		if i.wantPublicIP {
+			// A new public IP on every start, like an instance without an Elastic IP
+			hostOctet := aws.ToString(i.instance.PrivateIpAddress)[strings.LastIndex(aws.ToString(i.instance.PrivateIpAddress), ".")+1:]
+			publicIP := fmt.Sprintf("198.51.%d.%s", i.starts%256, hostOctet)
+			i.instance.PublicIpAddress = aws.String(publicIP)
+			i.instance.PublicDnsName = aws.String(fmt.Sprintf("ec2-%s.compute.amazonaws.com", strings.ReplaceAll(publicIP, ".", "-")))
+		}
+	case ec2types.InstanceStateNameStopping:
+		i.setState(ec2types.InstanceStateNameStopped)
+		i.instance.PublicIpAddress = nil
+		i.instance.PublicDnsName = nil
+	case ec2types.InstanceStateNameShuttingDown:
+		i.setState(ec2types.InstanceStateNameTerminated)
+		i.instance.PublicIpAddress = nil
+		i.instance.PublicDnsName = nil
+	}
+}
+
+func (i *fakeInstance) setState(state ec2types.InstanceStateName) {
+	i.instance.State = &ec2types.InstanceState{Name: state}
+}
+
+func (i *fakeInstance) stateChange(previous ec2types.InstanceStateName) ec2types.InstanceStateChange {
+	return ec2types.InstanceStateChange{
+		InstanceId:    i.instance.InstanceId,
+		PreviousState: &ec2types.InstanceState{Name: previous},
+		CurrentState:  &ec2types.InstanceState{Name: i.instance.State.Name},
+	}
+}
+
+// matchesFilters supports the DescribeInstances filters the operator uses. Unknown filters
+// fail, so a test notices when the operator starts relying on one the fake ignores.
+func matchesFilters(instance ec2types.Instance, filters []ec2types.Filter) (bool, error) {
+	for _, filter := range filters {
+		name := aws.ToString(filter.Name)
+		var value string
+		switch {
+		case name == "instance-state-name":
+			value = string(instance.State.Name)
+		case name == "instance-id":
+			value = aws.ToString(instance.InstanceId)
+		case strings.HasPrefix(name, "tag:"):
+			found := false
+			for _, tag := range instance.Tags {
+				if aws.ToString(tag.Key) == strings.TrimPrefix(name, "tag:") {
+					value = aws.ToString(tag.Value)
+					found = true
+				}
+			}
+			if !found {
+				return false, nil
+			}
+		default:
+			return false, apiError("InvalidParameterValue", "The filter '%s' is invalid", name)
+		}
+		if !slices.Contains(filter.Values, value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// fakeS3 is the S3 client of fakeAWS for one region
+type fakeS3 struct {
+	backend *fakeAWS
+	region  string
+}
+
+var _ S3API = &fakeS3{}
+
+func (c *fakeS3) CreateBucket(_ context.Context, params *s3.CreateBucketInput, _ ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	f := c.backend
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("CreateBucket"); err != nil {
+		return nil, err
+	}
+
+	bucketName := aws.ToString(params.Bucket)
+	if _, ok := f.buckets[bucketName]; ok {
+		return nil, apiError("BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.")
+	}
+
+	// Outside us-east-1 the location constraint has to name the region of the endpoint
+	location := ""
+	if params.CreateBucketConfiguration != nil {
+		location = string(params.CreateBucketConfiguration.LocationConstraint)
+	}
+	if (c.region == "us-east-1" && location != "") || (c.region != "us-east-1" && location != c.region) {
+		return nil, apiError("IllegalLocationConstraintException",
+			"The %s location constraint is incompatible for the region specific endpoint this request was sent to.", location)
+	}
+
+	// New buckets block public access, enforce bucket ownership and use SSE-S3
+	objectOwnership := params.ObjectOwnership
+	if objectOwnership == "" {
+		objectOwnership = s3types.ObjectOwnershipBucketOwnerEnforced
+	}
+	f.buckets[bucketName] = &fakeBucket{
+		region:          c.region,
+		objectOwnership: objectOwnership,
+		publicAccessBlock: &s3types.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(true),
+			IgnorePublicAcls:      aws.Bool(true),
+			BlockPublicPolicy:     aws.Bool(true),
+			RestrictPublicBuckets: aws.Bool(true),
+		},
+		acl: s3types.BucketCannedACLPrivate,
+		encryption: &s3types.ServerSideEncryptionConfiguration{
+			Rules: []s3types.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &s3types.ServerSideEncryptionByDefault{SSEAlgorithm: s3types.ServerSideEncryptionAes256},
+			}},
+		},
+	}
+	return &s3.CreateBucketOutput{Location: aws.String("/" + bucketName)}, nil
+}
+
+func (c *fakeS3) DeleteBucket(_ context.Context, params *s3.DeleteBucketInput, _ ...func(*s3.Options)) (*s3.DeleteBucketOutput, error) {
+	bucket, err := c.start("DeleteBucket", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(bucket.objects) > 0 {
+		return nil, apiError("BucketNotEmpty", "The bucket you tried to delete is not empty")
+	}
+	delete(c.backend.buckets, aws.ToString(params.Bucket))
+	return &s3.DeleteBucketOutput{}, nil
+}
+
+func (c *fakeS3) HeadBucket(_ context.Context, params *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	f := c.backend
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("HeadBucket"); err != nil {
+		return nil, err
+	}
+
+	// HeadBucket has no error body, the SDK reports a missing bucket as NotFound
+	bucket, ok := f.buckets[aws.ToString(params.Bucket)]
+	if !ok {
+		if f.lingering[aws.ToString(params.Bucket)] > 0 {
+			f.lingering[aws.ToString(params.Bucket)]--
+			return &s3.HeadBucketOutput{}, nil
+		}
+		return nil, &s3types.NotFound{Message: aws.String("Not Found")}
+	}
+	return &s3.HeadBucketOutput{BucketRegion: aws.String(bucket.region)}, nil
+}
+
+func (c *fakeS3) GetBucketLocation(_ context.Context, params *s3.GetBucketLocationInput, _ ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+	bucket, err := c.start("GetBucketLocation", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	// us-east-1 is reported as an empty location constraint
+	output := &s3.GetBucketLocationOutput{}
+	if bucket.region != "us-east-1" {
+		output.LocationConstraint = s3types.BucketLocationConstraint(bucket.region)
+	}
+	return output, nil
+}
+
+func (c *fakeS3) ListObjectVersions(_ context.Context, params *s3.ListObjectVersionsInput, _ ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	bucket, err := c.start("ListObjectVersions", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	maxKeys := int(aws.ToInt32(params.MaxKeys))
+	if maxKeys <= 0 || maxKeys > 1000 {
+		maxKeys = 1000
+	}
+	output := &s3.ListObjectVersionsOutput{}
+	for i, object := range bucket.objects {
+		if i == maxKeys {
+			output.IsTruncated = aws.Bool(true)
+			break
+		}
+		if object.deleteMarker {
+			output.DeleteMarkers = append(output.DeleteMarkers, s3types.DeleteMarkerEntry{
+				Key:       aws.String(object.key),
+				VersionId: aws.String(object.versionID),
+			})
+		} else {
+			output.Versions = append(output.Versions, s3types.ObjectVersion{
+				Key:       aws.String(object.key),
+				VersionId: aws.String(object.versionID),
+			})
+		}
+	}
+	return output, nil
+}
+
+func (c *fakeS3) DeleteObjects(_ context.Context, params *s3.DeleteObjectsInput, _ ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	bucket, err := c.start("DeleteObjects", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if params.Delete == nil || len(params.Delete.Objects) > 1000 {
+		return nil, apiError("MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+
+	output := &s3.DeleteObjectsOutput{}
+	for _, identifier := range params.Delete.Objects {
+		bucket.objects = slices.DeleteFunc(bucket.objects, func(o fakeObjectVersion) bool {
+			return o.key == aws.ToString(identifier.Key) && o.versionID == aws.ToString(identifier.VersionId)
+		})
+		if !aws.ToBool(params.Delete.Quiet) {
+			output.Deleted = append(output.Deleted, s3types.DeletedObject{Key: identifier.Key, VersionId: identifier.VersionId})
+		}
+	}
+	return output, nil
+}
+
+func (c *fakeS3) GetPublicAccessBlock(_ context.Context, params *s3.GetPublicAccessBlockInput, _ ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
+	bucket, err := c.start("GetPublicAccessBlock", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if bucket.publicAccessBlock == nil {
+		return nil, apiError("NoSuchPublicAccessBlockConfiguration", "The public access block configuration was not found")
+	}
+	config := *bucket.publicAccessBlock
+	return &s3.GetPublicAccessBlockOutput{PublicAccessBlockConfiguration: &config}, nil
+}
+
+func (c *fakeS3) PutPublicAccessBlock(_ context.Context, params *s3.PutPublicAccessBlockInput, _ ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
+	bucket, err := c.start("PutPublicAccessBlock", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	config := *params.PublicAccessBlockConfiguration
+	bucket.publicAccessBlock = &config
+	return &s3.PutPublicAccessBlockOutput{}, nil
+}
+
+func (c *fakeS3) GetBucketOwnershipControls(_ context.Context, params *s3.GetBucketOwnershipControlsInput, _ ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error) {
+	bucket, err := c.start("GetBucketOwnershipControls", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if bucket.objectOwnership == "" {
+		return nil, apiError("OwnershipControlsNotFoundError", "The bucket ownership controls were not found")
+	}
+	return &s3.GetBucketOwnershipControlsOutput{
+		OwnershipControls: &s3types.OwnershipControls{
+			Rules: []s3types.OwnershipControlsRule{{ObjectOwnership: bucket.objectOwnership}},
+		},
+	}, nil
+}
+
+func (c *fakeS3) PutBucketOwnershipControls(_ context.Context, params *s3.PutBucketOwnershipControlsInput, _ ...func(*s3.Options)) (*s3.PutBucketOwnershipControlsOutput, error) {
+	bucket, err := c.start("PutBucketOwnershipControls", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	bucket.objectOwnership = params.OwnershipControls.Rules[0].ObjectOwnership
+	return &s3.PutBucketOwnershipControlsOutput{}, nil
+}
+
+func (c *fakeS3) PutBucketAcl(_ context.Context, params *s3.PutBucketAclInput, _ ...func(*s3.Options)) (*s3.PutBucketAclOutput, error) {
+	bucket, err := c.start("PutBucketAcl", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if bucket.objectOwnership == s3types.ObjectOwnershipBucketOwnerEnforced && params.ACL != s3types.BucketCannedACLPrivate {
+		return nil, apiError("AccessControlListNotSupported", "The bucket does not allow ACLs")
+	}
+	publicACL := params.ACL == s3types.BucketCannedACLPublicRead || params.ACL == s3types.BucketCannedACLPublicReadWrite
+	if publicACL && aws.ToBool(bucket.publicAccessBlock.BlockPublicAcls) {
+		return nil, apiError("AccessDenied", "Access Denied")
+	}
+	bucket.acl = params.ACL
+	return &s3.PutBucketAclOutput{}, nil
+}
+
+func (c *fakeS3) GetBucketTagging(_ context.Context, params *s3.GetBucketTaggingInput, _ ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error) {
+	bucket, err := c.start("GetBucketTagging", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(bucket.tags) == 0 {
+		return nil, apiError("NoSuchTagSet", "The TagSet does not exist")
+	}
+	return &s3.GetBucketTaggingOutput{TagSet: slices.Clone(bucket.tags)}, nil
+}
+
+func (c *fakeS3) PutBucketTagging(_ context.Context, params *s3.PutBucketTaggingInput, _ ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error) {
+	bucket, err := c.start("PutBucketTagging", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range params.Tagging.TagSet {
+		if strings.HasPrefix(aws.ToString(tag.Key), "aws:") {
+			return nil, apiError("InvalidTag", "System tags cannot be added/updated by requester")
+		}
+	}
+	bucket.tags = slices.Clone(params.Tagging.TagSet)
+	return &s3.PutBucketTaggingOutput{}, nil
+}
+
+func (c *fakeS3) GetBucketVersioning(_ context.Context, params *s3.GetBucketVersioningInput, _ ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
+	bucket, err := c.start("GetBucketVersioning", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return &s3.GetBucketVersioningOutput{Status: bucket.versioning}, nil
+}
+
+func (c *fakeS3) PutBucketVersioning(_ context.Context, params *s3.PutBucketVersioningInput, _ ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
+	bucket, err := c.start("PutBucketVersioning", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	bucket.versioning = params.VersioningConfiguration.Status
+	return &s3.PutBucketVersioningOutput{}, nil
+}
+
+func (c *fakeS3) GetBucketPolicy(_ context.Context, params *s3.GetBucketPolicyInput, _ ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error) {
+	bucket, err := c.start("GetBucketPolicy", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if bucket.policy == nil {
+		return nil, apiError("NoSuchBucketPolicy", "The bucket policy does not exist")
+	}
+	return &s3.GetBucketPolicyOutput{Policy: aws.String(*bucket.policy)}, nil
+}
+
+func (c *fakeS3) PutBucketPolicy(_ context.Context, params *s3.PutBucketPolicyInput, _ ...func(*s3.Options)) (*s3.PutBucketPolicyOutput, error) {
+	bucket, err := c.start("PutBucketPolicy", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid([]byte(aws.ToString(params.Policy))) {
+		return nil, apiError("MalformedPolicy", "Policies must be valid JSON and the first byte must be '{'")
+	}
+	bucket.policy = aws.String(aws.ToString(params.Policy))
+	return &s3.PutBucketPolicyOutput{}, nil
+}
+
+func (c *fakeS3) DeleteBucketPolicy(_ context.Context, params *s3.DeleteBucketPolicyInput, _ ...func(*s3.Options)) (*s3.DeleteBucketPolicyOutput, error) {
+	bucket, err := c.start("DeleteBucketPolicy", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	bucket.policy = nil
+	return &s3.DeleteBucketPolicyOutput{}, nil
+}
+
+func (c *fakeS3) GetBucketWebsite(_ context.Context, params *s3.GetBucketWebsiteInput, _ ...func(*s3.Options)) (*s3.GetBucketWebsiteOutput, error) {
+	bucket, err := c.start("GetBucketWebsite", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if bucket.website == nil {
+		return nil, apiError("NoSuchWebsiteConfiguration", "The specified bucket does not have a website configuration")
+	}
+	return &s3.GetBucketWebsiteOutput{
+		IndexDocument:         bucket.website.IndexDocument,
+		ErrorDocument:         bucket.website.ErrorDocument,
+		RedirectAllRequestsTo: bucket.website.RedirectAllRequestsTo,
+		RoutingRules:          slices.Clone(bucket.website.RoutingRules),
+	}, nil
+}
+
+func (c *fakeS3) PutBucketWebsite(_ context.Context, params *s3.PutBucketWebsiteInput, _ ...func(*s3.Options)) (*s3.PutBucketWebsiteOutput, error) {
+	bucket, err := c.start("PutBucketWebsite", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	website := *params.WebsiteConfiguration
+	bucket.website = &website
+	return &s3.PutBucketWebsiteOutput{}, nil
+}
+
+func (c *fakeS3) DeleteBucketWebsite(_ context.Context, params *s3.DeleteBucketWebsiteInput, _ ...func(*s3.Options)) (*s3.DeleteBucketWebsiteOutput, error) {
+	bucket, err := c.start("DeleteBucketWebsite", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	bucket.website = nil
+	return &s3.DeleteBucketWebsiteOutput{}, nil
+}
+
+func (c *fakeS3) GetBucketLifecycleConfiguration(_ context.Context, params *s3.GetBucketLifecycleConfigurationInput, _ ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	bucket, err := c.start("GetBucketLifecycleConfiguration", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(bucket.lifecycle) == 0 {
+		return nil, apiError("NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist")
+	}
+	return &s3.GetBucketLifecycleConfigurationOutput{Rules: slices.Clone(bucket.lifecycle)}, nil
+}
+
+func (c *fakeS3) PutBucketLifecycleConfiguration(_ context.Context, params *s3.PutBucketLifecycleConfigurationInput, _ ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	bucket, err := c.start("PutBucketLifecycleConfiguration", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	bucket.lifecycle = slices.Clone(params.LifecycleConfiguration.Rules)
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+func (c *fakeS3) DeleteBucketLifecycle(_ context.Context, params *s3.DeleteBucketLifecycleInput, _ ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error) {
+	bucket, err := c.start("DeleteBucketLifecycle", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	bucket.lifecycle = nil
+	return &s3.DeleteBucketLifecycleOutput{}, nil
+}
+
+func (c *fakeS3) GetBucketCors(_ context.Context, params *s3.GetBucketCorsInput, _ ...func(*s3.Options)) (*s3.GetBucketCorsOutput, error) {
+	bucket, err := c.start("GetBucketCors", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(bucket.cors) == 0 {
+		return nil, apiError("NoSuchCORSConfiguration", "The CORS configuration does not exist")
+	}
+	return &s3.GetBucketCorsOutput{CORSRules: slices.Clone(bucket.cors)}, nil
+}
+
+func (c *fakeS3) PutBucketCors(_ context.Context, params *s3.PutBucketCorsInput, _ ...func(*s3.Options)) (*s3.PutBucketCorsOutput, error) {
+	bucket, err := c.start("PutBucketCors", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	bucket.cors = slices.Clone(params.CORSConfiguration.CORSRules)
+	return &s3.PutBucketCorsOutput{}, nil
+}
+
+func (c *fakeS3) DeleteBucketCors(_ context.Context, params *s3.DeleteBucketCorsInput, _ ...func(*s3.Options)) (*s3.DeleteBucketCorsOutput, error) {
+	bucket, err := c.start("DeleteBucketCors", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	bucket.cors = nil
+	return &s3.DeleteBucketCorsOutput{}, nil
+}
+
+func (c *fakeS3) GetBucketEncryption(_ context.Context, params *s3.GetBucketEncryptionInput, _ ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	bucket, err := c.start("GetBucketEncryption", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if bucket.encryption == nil {
+		return nil, apiError("ServerSideEncryptionConfigurationNotFoundError", "The server side encryption configuration was not found")
+	}
+	encryption := *bucket.encryption
+	return &s3.GetBucketEncryptionOutput{ServerSideEncryptionConfiguration: &encryption}, nil
+}
+
+func (c *fakeS3) PutBucketEncryption(_ context.Context, params *s3.PutBucketEncryptionInput, _ ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
+	bucket, err := c.start("PutBucketEncryption", params.Bucket)
+	defer c.backend.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	encryption := *params.ServerSideEncryptionConfiguration
+	bucket.encryption = &encryption
+	return &s3.PutBucketEncryptionOutput{}, nil
+}
+
+// start locks the backend, records the call and looks up the bucket it is about. The caller
+// unlocks the backend, also when an error is returned.
+func (c *fakeS3) start(operation string, bucketName *string) (*fakeBucket, error) {
+	f := c.backend
+	f.mu.Lock()
+	if err := f.record(operation); err != nil {
+		return nil, err
+	}
+	bucket, ok := f.buckets[aws.ToString(bucketName)]
+	if !ok {
+		return nil, apiError("NoSuchBucket", "The specified bucket does not exist")
+	}
+	return bucket, nil
+}
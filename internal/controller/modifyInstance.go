@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// stopEc2Instance asks AWS to stop (or hibernate) the instance and returns its new state
+// without waiting
+func stopEc2Instance(ctx context.Context, clients AWSClientProvider, ec2Instance *computev1.Ec2instance, hibernate bool) (string, error) {
+	l := logf.FromContext(ctx)
+
+	l.Info("Stopping EC2 instance", "instanceID", ec2Instance.Status.InstanceID, "hibernate", hibernate)
+
+	ec2Client, err := clients.EC2(ctx, ec2Instance.Status.Region)
+	if err != nil {
+		l.Error(err, "Failed to create EC2 client")
+		return "", err
+	}
+
+	stopInput := &ec2.StopInstancesInput{
+		InstanceIds: []string{ec2Instance.Status.InstanceID},
+	}
+	if hibernate {
+		stopInput.Hibernate = aws.Bool(true)
+	}
+
+	stopResult, err := ec2Client.StopInstances(ctx, stopInput)
+	if err != nil {
+		if awsErrorCode(err) == "UnsupportedHibernationConfiguration" {
+			return "", newInvalidSpecError("instance %s was not launched with hibernation enabled, set spec.hibernation before launch to use powerState hibernated",
+				ec2Instance.Status.InstanceID)
+		}
+		l.Error(err, "Failed to stop instance")
+		return "", fmt.Errorf("failed to stop EC2 instance: %w", err)
+	}
+
+	if len(stopResult.StoppingInstances) == 0 || stopResult.StoppingInstances[0].CurrentState == nil {
+		return "", nil
+	}
+	return string(stopResult.StoppingInstances[0].CurrentState.Name), nil
+}
+
+// startEc2Instance asks AWS to start the instance and returns its new state without waiting
+func startEc2Instance(ctx context.Context, clients AWSClientProvider, ec2Instance *computev1.Ec2instance) (string, error) {
+	l := logf.FromContext(ctx)
+
+	l.Info("Starting EC2 instance", "instanceID", ec2Instance.Status.InstanceID)
+
+	ec2Client, err := clients.EC2(ctx, ec2Instance.Status.Region)
+	if err != nil {
+		l.Error(err, "Failed to create EC2 client")
+		return "", err
+	}
+
+	startResult, err := ec2Client.StartInstances(ctx, &ec2.StartInstancesInput{
+		InstanceIds: []string{ec2Instance.Status.InstanceID},
+	})
+	if err != nil {
+		l.Error(err, "Failed to start instance")
+		return "", fmt.Errorf("failed to start EC2 instance: %w", err)
+	}
+
+	if len(startResult.StartingInstances) == 0 || startResult.StartingInstances[0].CurrentState == nil {
+		return "", nil
+	}
+	return string(startResult.StartingInstances[0].CurrentState.Name), nil
+}
+
+// modifyEc2InstanceType changes the instance type of a stopped instance to the one in the spec
+func modifyEc2InstanceType(ctx context.Context, clients AWSClientProvider, ec2Instance *computev1.Ec2instance) error {
+	l := logf.FromContext(ctx)
+
+	l.Info("Changing EC2 instance type",
+		"instanceID", ec2Instance.Status.InstanceID,
+		"from", ec2Instance.Status.InstanceType,
+		"to", ec2Instance.Spec.InstanceType)
+
+	ec2Client, err := clients.EC2(ctx, ec2Instance.Status.Region)
+	if err != nil {
+		l.Error(err, "Failed to create EC2 client")
+		return err
+	}
+
+	_, err = ec2Client.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId: aws.String(ec2Instance.Status.InstanceID),
+		InstanceType: &ec2types.AttributeValue{
+			Value: aws.String(ec2Instance.Spec.InstanceType),
+		},
+	})
+	if err != nil {
+		l.Error(err, "Failed to modify instance type")
+		return fmt.Errorf("failed to change instance type to %s: %w", ec2Instance.Spec.InstanceType, err)
+	}
+	return nil
+}
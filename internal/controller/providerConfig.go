@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+)
+
+// reasonProviderConfigInvalid is reported when spec.providerConfigRef can't be used
+const reasonProviderConfigInvalid = "ProviderConfigInvalid"
+
+// awsProvider is the AWS account a reconcile works in, resolved from a ProviderConfig
+type awsProvider struct {
+	providerConfig *computev1.ProviderConfig
+}
+
+type awsProviderKey struct{}
+
+// +kubebuilder:rbac:groups=compute.cloud.com,resources=providerconfigs,verbs=get;list;watch
+
+// withProviderConfig looks up the ProviderConfig named ref and returns a context that sends
+// every AWS call made with it to that account, together with the ProviderConfig. An empty ref
+// returns ctx unchanged, so AWS calls use the operator's own credentials. A missing
+// ProviderConfig, or one that does not allow namespace, is an invalid spec.
+func withProviderConfig(ctx context.Context, reader client.Reader, namespace, ref string) (context.Context, *computev1.ProviderConfig, error) {
+	if ref == "" {
+		return ctx, nil, nil
+	}
+
+	providerConfig := &computev1.ProviderConfig{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: ref}, providerConfig); err != nil {
+		if errors.IsNotFound(err) {
+			return ctx, nil, newInvalidSpecError("providerConfig %s does not exist", ref)
+		}
+		return ctx, nil, fmt.Errorf("failed to get providerConfig %s: %w", ref, err)
+	}
+
+	allowed := providerConfig.Spec.AllowedNamespaces
+	if len(allowed) > 0 && !slices.Contains(allowed, namespace) {
+		return ctx, nil, newInvalidSpecError("providerConfig %s cannot be used from namespace %s", ref, namespace)
+	}
+
+	return context.WithValue(ctx, awsProviderKey{}, &awsProvider{providerConfig: providerConfig}), providerConfig, nil
+}
+
+// providerCredentials builds the credential options for a ProviderConfig. The Default source
+// starts from the operator's own credentials, a Secret replaces them entirely, and AssumeRole
+// replaces the operator's role.
+func providerCredentials(operator AWSCredentialsOptions, providerConfig *computev1.ProviderConfig) AWSCredentialsOptions {
+	opts := operator
+
+	credentials := providerConfig.Spec.Credentials
+	if credentials.Source == computev1.CredentialsSourceSecret && credentials.SecretRef != nil {
+		opts = AWSCredentialsOptions{
+			SecretRef: &types.NamespacedName{
+				Namespace: credentials.SecretRef.Namespace,
+				Name:      credentials.SecretRef.Name,
+			},
+		}
+	}
+
+	if assumeRole := providerConfig.Spec.AssumeRole; assumeRole != nil {
+		opts.RoleARN = assumeRole.RoleARN
+		opts.ExternalID = assumeRole.ExternalID
+		opts.RoleSessionName = assumeRole.SessionName
+	}
+
+	return opts
+}
+
+// awsProviderFrom returns the account set by withProviderConfig, or nil for the operator's own
+func awsProviderFrom(ctx context.Context) *awsProvider {
+	provider, _ := ctx.Value(awsProviderKey{}).(*awsProvider)
+	return provider
+}
+
+// providerTags returns the default tags of the ProviderConfig the reconcile works through
+func providerTags(ctx context.Context) map[string]string {
+	if provider := awsProviderFrom(ctx); provider != nil {
+		return provider.providerConfig.Spec.Tags
+	}
+	return nil
+}
+
+// resolveRegion sets *region, the region recorded in status, to the region the resource lives
+// in: spec.region if set, otherwise the region already recorded, otherwise the region of the
+// ProviderConfig. Keeping the recorded region pins the resource to it even if the
+// ProviderConfig changes later.
+func resolveRegion(region *string, specRegion string, providerConfig *computev1.ProviderConfig) error {
+	switch {
+	case specRegion != "":
+		*region = specRegion
+	case *region != "":
+	case providerConfig != nil && providerConfig.Spec.Region != "":
+		*region = providerConfig.Spec.Region
+	default:
+		return newInvalidSpecError("spec.region is empty and no region is set on the providerConfig")
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+)
+
+var _ = Describe("ProviderConfig", func() {
+	ctx := context.Background()
+
+	newReader := func(providerConfig *computev1.ProviderConfig) client.Reader {
+		scheme := runtime.NewScheme()
+		Expect(computev1.AddToScheme(scheme)).To(Succeed())
+		return fake.NewClientBuilder().WithScheme(scheme).WithObjects(providerConfig).Build()
+	}
+
+	providerConfig := &computev1.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "staging"},
+		Spec: computev1.ProviderConfigSpec{
+			Credentials: computev1.ProviderCredentials{
+				Source:    computev1.CredentialsSourceSecret,
+				SecretRef: &computev1.SecretReference{Namespace: "operator-system", Name: "staging-keys"},
+			},
+			AssumeRole:        &computev1.AssumeRoleConfig{RoleARN: "arn:aws:iam::123456789012:role/ops", ExternalID: "ext"},
+			Region:            "eu-west-1",
+			Tags:              map[string]string{"env": "staging"},
+			AllowedNamespaces: []string{"team-a"},
+		},
+	}
+
+	It("should send AWS calls to the referenced account", func() {
+		providerCtx, found, err := withProviderConfig(ctx, newReader(providerConfig), "team-a", "staging")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.Name).To(Equal("staging"))
+
+		provider := awsProviderFrom(providerCtx)
+		Expect(provider).NotTo(BeNil())
+		Expect(providerTags(providerCtx)).To(HaveKeyWithValue("env", "staging"))
+
+		credentials := providerCredentials(AWSCredentialsOptions{RoleSessionName: "operator"}, provider.providerConfig)
+		Expect(credentials.SecretRef.String()).To(Equal("operator-system/staging-keys"))
+		Expect(credentials.RoleARN).To(Equal("arn:aws:iam::123456789012:role/ops"))
+		Expect(credentials.ExternalID).To(Equal("ext"))
+		// A Secret replaces the operator's credentials entirely
+		Expect(credentials.RoleSessionName).To(BeEmpty())
+	})
+
+	It("should reject namespaces that are not allowed and missing ProviderConfigs", func() {
+		_, _, err := withProviderConfig(ctx, newReader(providerConfig), "team-b", "staging")
+		Expect(isInvalidSpec(err)).To(BeTrue())
+
+		_, _, err = withProviderConfig(ctx, newReader(providerConfig), "team-a", "production")
+		Expect(isInvalidSpec(err)).To(BeTrue())
+	})
+
+	It("should leave the context alone without a reference", func() {
+		providerCtx, found, err := withProviderConfig(ctx, newReader(providerConfig), "team-a", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeNil())
+		Expect(awsProviderFrom(providerCtx)).To(BeNil())
+	})
+
+	It("should take an empty region from the ProviderConfig and keep it afterwards", func() {
+		region := ""
+		Expect(resolveRegion(&region, "", providerConfig)).To(Succeed())
+		Expect(region).To(Equal("eu-west-1"))
+
+		moved := providerConfig.DeepCopy()
+		moved.Spec.Region = "us-west-2"
+		Expect(resolveRegion(&region, "", moved)).To(Succeed())
+		Expect(region).To(Equal("eu-west-1"))
+
+		Expect(resolveRegion(&region, "ap-south-1", providerConfig)).To(Succeed())
+		Expect(region).To(Equal("ap-south-1"))
+
+		region = ""
+		Expect(isInvalidSpec(resolveRegion(&region, "", nil))).To(BeTrue())
+	})
+})
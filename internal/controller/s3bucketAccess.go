@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// applyBucketAccess applies Block Public Access, object ownership and the canned ACL, in that
+// order, and records what AWS has in configInfo.
+//
+// Block Public Access has to be relaxed before a public ACL is accepted, and ACLs are rejected
+// while ownership is BucketOwnerEnforced. Going the other way, enforced ownership is rejected
+// while the bucket ACL still grants access to others, so the ACL is reset first.
+func applyBucketAccess(ctx context.Context, s3Client S3API, s3Bucket *computev1.S3Bucket, configInfo *computev1.BucketConfigurationInfo) error {
+	l := logf.FromContext(ctx)
+	bucketName := s3Bucket.Spec.BucketName
+
+	desiredBlock := publicAccessBlockFromSpec(s3Bucket.Spec.PublicAccessBlock)
+	desiredOwnership := s3Bucket.Spec.ObjectOwnership
+	if desiredOwnership == "" {
+		desiredOwnership = string(s3types.ObjectOwnershipBucketOwnerEnforced)
+	}
+	if err := validateBucketAccess(s3Bucket.Spec.ACL, desiredOwnership, desiredBlock); err != nil {
+		return err
+	}
+
+	currentBlock, err := getPublicAccessBlock(ctx, s3Client, bucketName)
+	if err != nil {
+		return err
+	}
+	if *currentBlock != *desiredBlock {
+		l.Info("Updating S3 bucket public access block", "bucketName", bucketName, "publicAccessBlock", *desiredBlock)
+		if _, err := s3Client.PutPublicAccessBlock(ctx, &s3.PutPublicAccessBlockInput{
+			Bucket: aws.String(bucketName),
+			PublicAccessBlockConfiguration: &s3types.PublicAccessBlockConfiguration{
+				BlockPublicAcls:       aws.Bool(desiredBlock.BlockPublicAcls),
+				IgnorePublicAcls:      aws.Bool(desiredBlock.IgnorePublicAcls),
+				BlockPublicPolicy:     aws.Bool(desiredBlock.BlockPublicPolicy),
+				RestrictPublicBuckets: aws.Bool(desiredBlock.RestrictPublicBuckets),
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to set public access block of S3 bucket %s: %w", bucketName, err)
+		}
+	}
+	configInfo.PublicAccessBlock = desiredBlock
+
+	currentOwnership, err := getObjectOwnership(ctx, s3Client, bucketName)
+	if err != nil {
+		return err
+	}
+	enforced := desiredOwnership == string(s3types.ObjectOwnershipBucketOwnerEnforced)
+
+	if enforced && currentOwnership != desiredOwnership {
+		// Drop any grants left over from ACL mode before disabling ACLs
+		if err := putBucketACL(ctx, s3Client, bucketName, string(s3types.BucketCannedACLPrivate)); err != nil {
+			return err
+		}
+	}
+	if currentOwnership != desiredOwnership {
+		l.Info("Updating S3 bucket object ownership", "bucketName", bucketName, "from", currentOwnership, "to", desiredOwnership)
+		if _, err := s3Client.PutBucketOwnershipControls(ctx, &s3.PutBucketOwnershipControlsInput{
+			Bucket: aws.String(bucketName),
+			OwnershipControls: &s3types.OwnershipControls{
+				Rules: []s3types.OwnershipControlsRule{
+					{ObjectOwnership: s3types.ObjectOwnership(desiredOwnership)},
+				},
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to set object ownership of S3 bucket %s: %w", bucketName, err)
+		}
+	}
+	configInfo.ObjectOwnership = desiredOwnership
+
+	if !enforced && s3Bucket.Spec.ACL != "" {
+		if err := putBucketACL(ctx, s3Client, bucketName, s3Bucket.Spec.ACL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateBucketAccess rejects ACL settings that S3 would refuse with the requested ownership
+// and Block Public Access flags
+func validateBucketAccess(acl, objectOwnership string, block *computev1.PublicAccessBlockStatus) error {
+	if acl == "" || acl == string(s3types.BucketCannedACLPrivate) {
+		return nil
+	}
+	if objectOwnership == string(s3types.ObjectOwnershipBucketOwnerEnforced) {
+		return newInvalidSpecError("acl %s needs objectOwnership BucketOwnerPreferred or ObjectWriter, ACLs are disabled with BucketOwnerEnforced", acl)
+	}
+	isPublic := acl == string(s3types.BucketCannedACLPublicRead) || acl == string(s3types.BucketCannedACLPublicReadWrite)
+	if isPublic && block.BlockPublicAcls {
+		return newInvalidSpecError("acl %s is public, set publicAccessBlock.blockPublicAcls to false to allow it", acl)
+	}
+	return nil
+}
+
+// publicAccessBlockFromSpec resolves the spec flags, treating unset flags as true
+func publicAccessBlockFromSpec(config computev1.PublicAccessBlockConfig) *computev1.PublicAccessBlockStatus {
+	boolOrTrue := func(b *bool) bool {
+		return b == nil || *b
+	}
+	return &computev1.PublicAccessBlockStatus{
+		BlockPublicAcls:       boolOrTrue(config.BlockPublicAcls),
+		IgnorePublicAcls:      boolOrTrue(config.IgnorePublicAcls),
+		BlockPublicPolicy:     boolOrTrue(config.BlockPublicPolicy),
+		RestrictPublicBuckets: boolOrTrue(config.RestrictPublicBuckets),
+	}
+}
+
+// getPublicAccessBlock returns the Block Public Access flags of the bucket. A bucket without a
+// configuration has every flag off.
+func getPublicAccessBlock(ctx context.Context, s3Client S3API, bucketName string) (*computev1.PublicAccessBlockStatus, error) {
+	output, err := s3Client.GetPublicAccessBlock(ctx, &s3.GetPublicAccessBlockInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		if awsErrorCode(err) == "NoSuchPublicAccessBlockConfiguration" {
+			return &computev1.PublicAccessBlockStatus{}, nil
+		}
+		return nil, fmt.Errorf("failed to get public access block of S3 bucket %s: %w", bucketName, err)
+	}
+
+	config := output.PublicAccessBlockConfiguration
+	if config == nil {
+		return &computev1.PublicAccessBlockStatus{}, nil
+	}
+	return &computev1.PublicAccessBlockStatus{
+		BlockPublicAcls:       aws.ToBool(config.BlockPublicAcls),
+		IgnorePublicAcls:      aws.ToBool(config.IgnorePublicAcls),
+		BlockPublicPolicy:     aws.ToBool(config.BlockPublicPolicy),
+		RestrictPublicBuckets: aws.ToBool(config.RestrictPublicBuckets),
+	}, nil
+}
+
+// getObjectOwnership returns the object ownership of the bucket. Buckets without ownership
+// controls behave like ObjectWriter.
+func getObjectOwnership(ctx context.Context, s3Client S3API, bucketName string) (string, error) {
+	output, err := s3Client.GetBucketOwnershipControls(ctx, &s3.GetBucketOwnershipControlsInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		if awsErrorCode(err) == "OwnershipControlsNotFoundError" {
+			return string(s3types.ObjectOwnershipObjectWriter), nil
+		}
+		return "", fmt.Errorf("failed to get object ownership of S3 bucket %s: %w", bucketName, err)
+	}
+	if output.OwnershipControls == nil || len(output.OwnershipControls.Rules) == 0 {
+		return string(s3types.ObjectOwnershipObjectWriter), nil
+	}
+	return string(output.OwnershipControls.Rules[0].ObjectOwnership), nil
+}
+
+func putBucketACL(ctx context.Context, s3Client S3API, bucketName, acl string) error {
+	logf.FromContext(ctx).Info("Applying S3 bucket ACL", "bucketName", bucketName, "acl", acl)
+	if _, err := s3Client.PutBucketAcl(ctx, &s3.PutBucketAclInput{
+		Bucket: aws.String(bucketName),
+		ACL:    s3types.BucketCannedACL(acl),
+	}); err != nil {
+		return fmt.Errorf("failed to set ACL of S3 bucket %s to %s: %w", bucketName, acl, err)
+	}
+	return nil
+}
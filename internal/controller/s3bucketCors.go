@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// applyBucketCORS replaces the bucket CORS configuration with the rules from the spec when it
+// differs from what the bucket has, and returns how many rules were applied. When the spec has no rules, a configuration the operator
+// applied earlier (appliedRules > 0) is removed; one set up outside the operator is left alone.
+func applyBucketCORS(ctx context.Context, s3Client S3API, s3Bucket *computev1.S3Bucket, appliedRules int32) (int32, error) {
+	l := logf.FromContext(ctx)
+
+	if len(s3Bucket.Spec.CORSRules) == 0 {
+		if appliedRules > 0 {
+			l.Info("Removing S3 bucket CORS configuration", "bucketName", s3Bucket.Spec.BucketName)
+			if _, err := s3Client.DeleteBucketCors(ctx, &s3.DeleteBucketCorsInput{
+				Bucket: aws.String(s3Bucket.Spec.BucketName),
+			}); err != nil {
+				return 0, fmt.Errorf("failed to delete CORS configuration of S3 bucket %s: %w", s3Bucket.Spec.BucketName, err)
+			}
+		}
+		return 0, nil
+	}
+
+	rules := buildCORSRules(s3Bucket.Spec.CORSRules)
+
+	current, err := getBucketCORSRules(ctx, s3Client, s3Bucket.Spec.BucketName)
+	if err != nil {
+		return 0, err
+	}
+	if slices.EqualFunc(current, rules, corsRulesEqual) {
+		return int32(len(rules)), nil
+	}
+
+	l.Info("Applying S3 bucket CORS configuration", "bucketName", s3Bucket.Spec.BucketName, "rules", len(rules))
+	if _, err := s3Client.PutBucketCors(ctx, &s3.PutBucketCorsInput{
+		Bucket: aws.String(s3Bucket.Spec.BucketName),
+		CORSConfiguration: &s3types.CORSConfiguration{
+			CORSRules: rules,
+		},
+	}); err != nil {
+		if awsErrorCode(err) == "InvalidRequest" || awsErrorCode(err) == "MalformedXML" {
+			return 0, newInvalidSpecError("CORS rules rejected by S3: %v", err)
+		}
+		return 0, fmt.Errorf("failed to put CORS configuration of S3 bucket %s: %w", s3Bucket.Spec.BucketName, err)
+	}
+
+	return int32(len(rules)), nil
+}
+
+// buildCORSRules converts spec.corsRules into S3 CORS rules
+func buildCORSRules(specRules []computev1.CORSRule) []s3types.CORSRule {
+	rules := make([]s3types.CORSRule, 0, len(specRules))
+	for _, specRule := range specRules {
+		rule := s3types.CORSRule{
+			ID:             stringOrNil(specRule.ID),
+			AllowedOrigins: specRule.AllowedOrigins,
+			AllowedMethods: specRule.AllowedMethods,
+			AllowedHeaders: specRule.AllowedHeaders,
+			ExposeHeaders:  specRule.ExposeHeaders,
+		}
+		if specRule.MaxAgeSeconds > 0 {
+			rule.MaxAgeSeconds = aws.Int32(specRule.MaxAgeSeconds)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// getBucketCORSRules returns the CORS rules of the bucket, or nil if it has none
+func getBucketCORSRules(ctx context.Context, s3Client S3API, bucketName string) ([]s3types.CORSRule, error) {
+	output, err := s3Client.GetBucketCors(ctx, &s3.GetBucketCorsInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		if awsErrorCode(err) == "NoSuchCORSConfiguration" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get CORS configuration of S3 bucket %s: %w", bucketName, err)
+	}
+	return output.CORSRules, nil
+}
+
+// corsRulesEqual compares the fields of two CORS rules that buildCORSRules sets
+func corsRulesEqual(a, b s3types.CORSRule) bool {
+	return aws.ToString(a.ID) == aws.ToString(b.ID) &&
+		slices.Equal(a.AllowedOrigins, b.AllowedOrigins) &&
+		slices.Equal(a.AllowedMethods, b.AllowedMethods) &&
+		slices.Equal(a.AllowedHeaders, b.AllowedHeaders) &&
+		slices.Equal(a.ExposeHeaders, b.ExposeHeaders) &&
+		aws.ToInt32(a.MaxAgeSeconds) == aws.ToInt32(b.MaxAgeSeconds)
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// applyBucketEncryption sets the default server-side encryption to match spec.encryption and
+// returns the encryption the bucket has afterwards. A nil spec.encryption leaves it alone.
+func applyBucketEncryption(ctx context.Context, s3Client S3API, s3Bucket *computev1.S3Bucket) (*computev1.BucketEncryptionStatus, error) {
+	l := logf.FromContext(ctx)
+
+	current, err := getBucketEncryption(ctx, s3Client, s3Bucket.Spec.BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	if s3Bucket.Spec.Encryption == nil {
+		return current, nil
+	}
+	desired := desiredBucketEncryption(s3Bucket.Spec.Encryption)
+	if current != nil && *current == *desired {
+		return current, nil
+	}
+
+	l.Info("Updating S3 bucket encryption", "bucketName", s3Bucket.Spec.BucketName, "algorithm", desired.Algorithm, "kmsKeyID", desired.KMSKeyID)
+	if _, err := s3Client.PutBucketEncryption(ctx, &s3.PutBucketEncryptionInput{
+		Bucket: aws.String(s3Bucket.Spec.BucketName),
+		ServerSideEncryptionConfiguration: &s3types.ServerSideEncryptionConfiguration{
+			Rules: []s3types.ServerSideEncryptionRule{
+				{
+					ApplyServerSideEncryptionByDefault: &s3types.ServerSideEncryptionByDefault{
+						SSEAlgorithm:   s3types.ServerSideEncryption(desired.Algorithm),
+						KMSMasterKeyID: stringOrNil(desired.KMSKeyID),
+					},
+					BucketKeyEnabled: aws.Bool(desired.BucketKeyEnabled),
+				},
+			},
+		},
+	}); err != nil {
+		// An unknown or inaccessible KMS key is a spec problem, retrying will not fix it
+		if awsErrorCode(err) == "InvalidArgument" || awsErrorCode(err) == "KMS.NotFoundException" {
+			return nil, newInvalidSpecError("encryption rejected by S3: %v", err)
+		}
+		return nil, fmt.Errorf("failed to set encryption of S3 bucket %s: %w", s3Bucket.Spec.BucketName, err)
+	}
+
+	return desired, nil
+}
+
+// desiredBucketEncryption resolves spec.encryption into what AWS should report for it
+func desiredBucketEncryption(encryption *computev1.BucketEncryption) *computev1.BucketEncryptionStatus {
+	desired := &computev1.BucketEncryptionStatus{
+		Algorithm:        encryption.Algorithm,
+		KMSKeyID:         encryption.KMSKeyID,
+		BucketKeyEnabled: encryption.BucketKeyEnabled,
+	}
+	if desired.Algorithm == "" {
+		desired.Algorithm = string(s3types.ServerSideEncryptionAes256)
+	}
+	return desired
+}
+
+// getBucketEncryption returns the default encryption of the bucket, or nil if it has none
+func getBucketEncryption(ctx context.Context, s3Client S3API, bucketName string) (*computev1.BucketEncryptionStatus, error) {
+	output, err := s3Client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		if awsErrorCode(err) == "ServerSideEncryptionConfigurationNotFoundError" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get encryption of S3 bucket %s: %w", bucketName, err)
+	}
+	if output.ServerSideEncryptionConfiguration == nil || len(output.ServerSideEncryptionConfiguration.Rules) == 0 {
+		return nil, nil
+	}
+
+	rule := output.ServerSideEncryptionConfiguration.Rules[0]
+	if rule.ApplyServerSideEncryptionByDefault == nil {
+		return nil, nil
+	}
+	return &computev1.BucketEncryptionStatus{
+		Algorithm:        string(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm),
+		KMSKeyID:         aws.ToString(rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID),
+		BucketKeyEnabled: aws.ToBool(rule.BucketKeyEnabled),
+	}, nil
+}
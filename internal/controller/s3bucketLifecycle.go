@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// storageClassRuleID names the lifecycle rule generated from spec.storageClass
+const storageClassRuleID = "operator-storage-class"
+
+// applyBucketLifecycle replaces the bucket lifecycle configuration with the rules from the spec
+// when it differs from what the bucket has, and returns how many rules were applied. When the
+// spec has no rules, a configuration the operator applied earlier (appliedRules > 0) is removed;
+// one set up outside the operator is left alone.
+func applyBucketLifecycle(ctx context.Context, s3Client S3API, s3Bucket *computev1.S3Bucket, appliedRules int32) (int32, error) {
+	l := logf.FromContext(ctx)
+
+	rules, err := buildLifecycleRules(s3Bucket)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(rules) == 0 {
+		if appliedRules > 0 {
+			l.Info("Removing S3 bucket lifecycle configuration", "bucketName", s3Bucket.Spec.BucketName)
+			if _, err := s3Client.DeleteBucketLifecycle(ctx, &s3.DeleteBucketLifecycleInput{
+				Bucket: aws.String(s3Bucket.Spec.BucketName),
+			}); err != nil {
+				return 0, fmt.Errorf("failed to delete lifecycle configuration of S3 bucket %s: %w", s3Bucket.Spec.BucketName, err)
+			}
+		}
+		return 0, nil
+	}
+
+	current, err := getBucketLifecycleRules(ctx, s3Client, s3Bucket.Spec.BucketName)
+	if err != nil {
+		return 0, err
+	}
+	if lifecycleRulesEqual(current, rules) {
+		return int32(len(rules)), nil
+	}
+
+	l.Info("Applying S3 bucket lifecycle configuration", "bucketName", s3Bucket.Spec.BucketName, "rules", len(rules))
+	if _, err := s3Client.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(s3Bucket.Spec.BucketName),
+		LifecycleConfiguration: &s3types.BucketLifecycleConfiguration{
+			Rules: rules,
+		},
+	}); err != nil {
+		if awsErrorCode(err) == "InvalidArgument" || awsErrorCode(err) == "InvalidRequest" {
+			return 0, newInvalidSpecError("lifecycle rules rejected by S3: %v", err)
+		}
+		return 0, fmt.Errorf("failed to put lifecycle configuration of S3 bucket %s: %w", s3Bucket.Spec.BucketName, err)
+	}
+
+	return int32(len(rules)), nil
+}
+
+// getBucketLifecycleRules returns the lifecycle rules of the bucket, or nil if it has none
+func getBucketLifecycleRules(ctx context.Context, s3Client S3API, bucketName string) ([]s3types.LifecycleRule, error) {
+	output, err := s3Client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		if awsErrorCode(err) == "NoSuchLifecycleConfiguration" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get lifecycle configuration of S3 bucket %s: %w", bucketName, err)
+	}
+	return output.Rules, nil
+}
+
+// lifecycleRuleFields holds the parts of a lifecycle rule the operator sets, in a form that
+// compares equal however S3 echoes the rule back
+type lifecycleRuleFields struct {
+	status                          s3types.ExpirationStatus
+	prefix                          string
+	transitions                     string
+	expirationDays                  int32
+	noncurrentVersionExpirationDays int32
+	abortIncompleteMultipartDays    int32
+}
+
+// lifecycleRulesEqual reports whether the rules a bucket has match the rules built from the
+// spec. Rules are matched by ID, so S3 returning them in another order is not a difference.
+func lifecycleRulesEqual(current, desired []s3types.LifecycleRule) bool {
+	if len(current) != len(desired) {
+		return false
+	}
+	currentByID := make(map[string]lifecycleRuleFields, len(current))
+	for _, rule := range current {
+		currentByID[aws.ToString(rule.ID)] = lifecycleFields(rule)
+	}
+	for _, rule := range desired {
+		fields, ok := currentByID[aws.ToString(rule.ID)]
+		if !ok || fields != lifecycleFields(rule) {
+			return false
+		}
+	}
+	return true
+}
+
+// lifecycleFields extracts the fields compared by lifecycleRulesEqual from a rule
+func lifecycleFields(rule s3types.LifecycleRule) lifecycleRuleFields {
+	fields := lifecycleRuleFields{status: rule.Status}
+	if rule.Filter != nil {
+		fields.prefix = aws.ToString(rule.Filter.Prefix)
+	}
+	transitions := make([]string, 0, len(rule.Transitions))
+	for _, transition := range rule.Transitions {
+		transitions = append(transitions, fmt.Sprintf("%d:%s", aws.ToInt32(transition.Days), transition.StorageClass))
+	}
+	slices.Sort(transitions)
+	fields.transitions = fmt.Sprint(transitions)
+	if rule.Expiration != nil {
+		fields.expirationDays = aws.ToInt32(rule.Expiration.Days)
+	}
+	if rule.NoncurrentVersionExpiration != nil {
+		fields.noncurrentVersionExpirationDays = aws.ToInt32(rule.NoncurrentVersionExpiration.NoncurrentDays)
+	}
+	if rule.AbortIncompleteMultipartUpload != nil {
+		fields.abortIncompleteMultipartDays = aws.ToInt32(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation)
+	}
+	return fields
+}
+
+// buildLifecycleRules converts spec.lifecycleRules, plus a rule for spec.storageClass, into
+// S3 lifecycle rules
+func buildLifecycleRules(s3Bucket *computev1.S3Bucket) ([]s3types.LifecycleRule, error) {
+	var rules []s3types.LifecycleRule
+
+	storageClassRule, err := storageClassLifecycleRule(s3Bucket.Spec.StorageClass)
+	if err != nil {
+		return nil, err
+	}
+	if storageClassRule != nil {
+		rules = append(rules, *storageClassRule)
+	}
+
+	for i, specRule := range s3Bucket.Spec.LifecycleRules {
+		id := specRule.ID
+		if id == "" {
+			id = fmt.Sprintf("rule-%d", i)
+		}
+
+		rule := s3types.LifecycleRule{
+			ID:     aws.String(id),
+			Status: s3types.ExpirationStatusEnabled,
+			Filter: &s3types.LifecycleRuleFilter{
+				Prefix: aws.String(specRule.Prefix),
+			},
+		}
+		for _, transition := range specRule.Transitions {
+			rule.Transitions = append(rule.Transitions, s3types.Transition{
+				Days:         aws.Int32(transition.Days),
+				StorageClass: s3types.TransitionStorageClass(transition.StorageClass),
+			})
+		}
+		if specRule.ExpirationDays > 0 {
+			rule.Expiration = &s3types.LifecycleExpiration{
+				Days: aws.Int32(specRule.ExpirationDays),
+			}
+		}
+		if specRule.NoncurrentVersionExpirationDays > 0 {
+			rule.NoncurrentVersionExpiration = &s3types.NoncurrentVersionExpiration{
+				NoncurrentDays: aws.Int32(specRule.NoncurrentVersionExpirationDays),
+			}
+		}
+		if specRule.AbortIncompleteMultipartUploadDays > 0 {
+			rule.AbortIncompleteMultipartUpload = &s3types.AbortIncompleteMultipartUpload{
+				DaysAfterInitiation: aws.Int32(specRule.AbortIncompleteMultipartUploadDays),
+			}
+		}
+
+		if len(rule.Transitions) == 0 && rule.Expiration == nil &&
+			rule.NoncurrentVersionExpiration == nil && rule.AbortIncompleteMultipartUpload == nil {
+			return nil, newInvalidSpecError("lifecycle rule %s has no transitions or expirations", id)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// storageClassLifecycleRule turns spec.storageClass into a rule transitioning every object to
+// that class. S3 has no bucket default storage class, so this is the closest equivalent.
+// The infrequent access classes only accept objects that are at least 30 days old.
+func storageClassLifecycleRule(storageClass string) (*s3types.LifecycleRule, error) {
+	var days int32
+	switch s3types.TransitionStorageClass(storageClass) {
+	case "", s3types.TransitionStorageClass(s3types.StorageClassStandard):
+		return nil, nil
+	case s3types.TransitionStorageClassStandardIa, s3types.TransitionStorageClassOnezoneIa:
+		days = 30
+	case s3types.TransitionStorageClassGlacier, s3types.TransitionStorageClassGlacierIr,
+		s3types.TransitionStorageClassDeepArchive, s3types.TransitionStorageClassIntelligentTiering:
+		days = 0
+	default:
+		return nil, newInvalidSpecError("storage class %s cannot be used as a default, S3 can only transition objects to GLACIER, GLACIER_IR, DEEP_ARCHIVE, INTELLIGENT_TIERING, STANDARD_IA or ONEZONE_IA", storageClass)
+	}
+
+	return &s3types.LifecycleRule{
+		ID:     aws.String(storageClassRuleID),
+		Status: s3types.ExpirationStatusEnabled,
+		Filter: &s3types.LifecycleRuleFilter{
+			Prefix: aws.String(""),
+		},
+		Transitions: []s3types.Transition{
+			{
+				Days:         aws.Int32(days),
+				StorageClass: s3types.TransitionStorageClass(storageClass),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,261 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// policyVersion is the IAM policy language version used for generated policies
+const policyVersion = "2012-10-17"
+
+// defaultPolicyVersion is the version IAM assumes for policies that do not name one
+const defaultPolicyVersion = "2008-10-17"
+
+// accountIDPattern matches a bare AWS account ID, which S3 expands to the account's root ARN
+var accountIDPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+// policyDocument and policyStatement are the JSON shape of a generated bucket policy
+type policyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+type policyStatement struct {
+	Sid       string                         `json:"Sid,omitempty"`
+	Effect    string                         `json:"Effect"`
+	Principal interface{}                    `json:"Principal"`
+	Action    []string                       `json:"Action"`
+	Resource  []string                       `json:"Resource"`
+	Condition map[string]map[string][]string `json:"Condition,omitempty"`
+}
+
+// applyBucketPolicy puts the policy from spec.policy and returns whether the bucket now has a
+// policy managed by the operator. When spec.policy is removed, a policy the operator applied
+// earlier (policyApplied) is deleted; one set up outside the operator is left alone.
+func applyBucketPolicy(ctx context.Context, s3Client S3API, s3Bucket *computev1.S3Bucket, policyApplied bool) (bool, error) {
+	l := logf.FromContext(ctx)
+	bucketName := s3Bucket.Spec.BucketName
+
+	if s3Bucket.Spec.Policy == nil {
+		if policyApplied {
+			l.Info("Removing S3 bucket policy", "bucketName", bucketName)
+			if _, err := s3Client.DeleteBucketPolicy(ctx, &s3.DeleteBucketPolicyInput{
+				Bucket: aws.String(bucketName),
+			}); err != nil {
+				return true, fmt.Errorf("failed to delete policy of S3 bucket %s: %w", bucketName, err)
+			}
+		}
+		return false, nil
+	}
+
+	desired, err := buildBucketPolicy(bucketName, s3Bucket.Spec.Policy)
+	if err != nil {
+		return policyApplied, err
+	}
+
+	current, err := getBucketPolicy(ctx, s3Client, bucketName)
+	if err != nil {
+		return policyApplied, err
+	}
+	if current != "" && policiesEqual(current, desired) {
+		return true, nil
+	}
+
+	l.Info("Applying S3 bucket policy", "bucketName", bucketName)
+	if _, err := s3Client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+		Bucket: aws.String(bucketName),
+		Policy: aws.String(desired),
+	}); err != nil {
+		if awsErrorCode(err) == "MalformedPolicy" {
+			return policyApplied, newInvalidSpecError("bucket policy rejected by S3: %v", err)
+		}
+		return policyApplied, fmt.Errorf("failed to put policy of S3 bucket %s: %w", bucketName, err)
+	}
+
+	return true, nil
+}
+
+// buildBucketPolicy returns the policy document for spec.policy, validating raw JSON and
+// rendering structured statements against the bucket ARN
+func buildBucketPolicy(bucketName string, policy *computev1.BucketPolicy) (string, error) {
+	if policy.JSON != "" {
+		var document map[string]interface{}
+		if err := json.Unmarshal([]byte(policy.JSON), &document); err != nil {
+			return "", newInvalidSpecError("policy.json is not a valid JSON object: %v", err)
+		}
+		if _, ok := document["Statement"]; !ok {
+			return "", newInvalidSpecError("policy.json has no Statement")
+		}
+		return policy.JSON, nil
+	}
+
+	if len(policy.Statements) == 0 {
+		return "", newInvalidSpecError("policy needs either json or statements")
+	}
+
+	bucketARN := fmt.Sprintf("arn:aws:s3:::%s", bucketName)
+	document := policyDocument{Version: policyVersion}
+	for i, specStatement := range policy.Statements {
+		if len(specStatement.Principals) == 0 || len(specStatement.Actions) == 0 {
+			return "", newInvalidSpecError("policy statement %d needs at least one principal and one action", i)
+		}
+
+		statement := policyStatement{
+			Sid:       specStatement.Sid,
+			Effect:    specStatement.Effect,
+			Action:    specStatement.Actions,
+			Condition: specStatement.Conditions,
+		}
+		if statement.Effect == "" {
+			statement.Effect = "Allow"
+		}
+
+		if len(specStatement.Principals) == 1 && specStatement.Principals[0] == "*" {
+			statement.Principal = "*"
+		} else {
+			statement.Principal = map[string][]string{"AWS": specStatement.Principals}
+		}
+
+		if len(specStatement.ResourcePrefixes) == 0 {
+			statement.Resource = []string{bucketARN, bucketARN + "/*"}
+		} else {
+			for _, prefix := range specStatement.ResourcePrefixes {
+				statement.Resource = append(statement.Resource, fmt.Sprintf("%s/%s*", bucketARN, prefix))
+			}
+		}
+
+		document.Statement = append(document.Statement, statement)
+	}
+
+	rendered, err := json.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("failed to render bucket policy: %w", err)
+	}
+	return string(rendered), nil
+}
+
+// getBucketPolicy returns the current policy of the bucket, or "" if it has none
+func getBucketPolicy(ctx context.Context, s3Client S3API, bucketName string) (string, error) {
+	output, err := s3Client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		if awsErrorCode(err) == "NoSuchBucketPolicy" {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get policy of S3 bucket %s: %w", bucketName, err)
+	}
+	return aws.ToString(output.Policy), nil
+}
+
+// policiesEqual compares two policy documents ignoring formatting and the rewrites S3 makes
+// when it stores a policy, so a policy read back from S3 matches the one that was put
+func policiesEqual(a, b string) bool {
+	var decodedA, decodedB map[string]interface{}
+	if err := json.Unmarshal([]byte(a), &decodedA); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(b), &decodedB); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalizePolicy(decodedA), normalizePolicy(decodedB))
+}
+
+// normalizePolicy rewrites a decoded policy document into one canonical form: the version
+// defaulted, empty Sids dropped, "*" principals written as {"AWS": "*"}, account IDs expanded to
+// root ARNs, lists of strings sorted and single element lists replaced by their element.
+func normalizePolicy(document map[string]interface{}) interface{} {
+	if _, ok := document["Version"]; !ok {
+		document["Version"] = defaultPolicyVersion
+	}
+
+	statements, ok := document["Statement"].([]interface{})
+	if !ok {
+		statements = []interface{}{document["Statement"]}
+	}
+	for _, s := range statements {
+		statement, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if sid, ok := statement["Sid"]; ok && sid == "" {
+			delete(statement, "Sid")
+		}
+		for _, key := range []string{"Principal", "NotPrincipal"} {
+			if principal, ok := statement[key]; ok {
+				statement[key] = normalizePrincipal(principal)
+			}
+		}
+	}
+	document["Statement"] = statements
+
+	return normalizePolicyValue(document)
+}
+
+// normalizePrincipal writes a "*" principal as {"AWS": "*"} and expands AWS account IDs to
+// the root ARN of the account, as S3 does
+func normalizePrincipal(principal interface{}) interface{} {
+	if principal == "*" {
+		return map[string]interface{}{"AWS": "*"}
+	}
+	principals, ok := principal.(map[string]interface{})
+	if !ok {
+		return principal
+	}
+
+	expand := func(value interface{}) interface{} {
+		if id, ok := value.(string); ok && accountIDPattern.MatchString(id) {
+			return fmt.Sprintf("arn:aws:iam::%s:root", id)
+		}
+		return value
+	}
+	switch awsPrincipals := principals["AWS"].(type) {
+	case string:
+		principals["AWS"] = expand(awsPrincipals)
+	case []interface{}:
+		for i, value := range awsPrincipals {
+			awsPrincipals[i] = expand(value)
+		}
+	}
+	return principals
+}
+
+// normalizePolicyValue sorts lists of strings and replaces single element lists by their
+// element, at every level of a decoded policy
+func normalizePolicyValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			typed[key] = normalizePolicyValue(nested)
+		}
+		return typed
+	case []interface{}:
+		if len(typed) == 1 {
+			return normalizePolicyValue(typed[0])
+		}
+		strs := make([]string, 0, len(typed))
+		for i, nested := range typed {
+			typed[i] = normalizePolicyValue(nested)
+			if str, ok := typed[i].(string); ok {
+				strs = append(strs, str)
+			}
+		}
+		if len(strs) == len(typed) {
+			sort.Strings(strs)
+			for i, str := range strs {
+				typed[i] = str
+			}
+		}
+		return typed
+	}
+	return value
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// maxBucketTags is the most tags S3 allows on a bucket
+const maxBucketTags = 50
+
+// applyBucketTags keeps the operator managed tags on the bucket in sync and returns them.
+// managedTags are the tags applied on the previous sync; keys dropped since then are removed,
+// while tags the operator never set (for example on an adopted bucket) are kept.
+func applyBucketTags(ctx context.Context, s3Client S3API, s3Bucket *computev1.S3Bucket, managedTags map[string]string) (map[string]string, error) {
+	l := logf.FromContext(ctx)
+	bucketName := s3Bucket.Spec.BucketName
+
+	desired := resourceTags(s3Bucket, providerTags(ctx), s3Bucket.Spec.Tags)
+
+	current, err := getBucketTags(ctx, s3Client, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := mergeBucketTags(current, managedTags, desired)
+	if len(merged) > maxBucketTags {
+		return nil, newInvalidSpecError("bucket would have %d tags, S3 allows at most %d", len(merged), maxBucketTags)
+	}
+	if maps.Equal(current, merged) {
+		return desired, nil
+	}
+
+	tagSet := make([]s3types.Tag, 0, len(merged))
+	for _, key := range sortedTagKeys(merged) {
+		tagSet = append(tagSet, s3types.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(merged[key]),
+		})
+	}
+
+	l.Info("Updating S3 bucket tags", "bucketName", bucketName, "tags", len(tagSet))
+	if _, err := s3Client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucketName),
+		Tagging: &s3types.Tagging{TagSet: tagSet},
+	}); err != nil {
+		if awsErrorCode(err) == "InvalidTag" {
+			return nil, newInvalidSpecError("tags rejected by S3: %v", err)
+		}
+		return nil, fmt.Errorf("failed to tag S3 bucket %s: %w", bucketName, err)
+	}
+
+	return desired, nil
+}
+
+// getBucketTags returns the tags on the bucket. A bucket without tags returns an empty map.
+func getBucketTags(ctx context.Context, s3Client S3API, bucketName string) (map[string]string, error) {
+	taggingOutput, err := s3Client.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil && awsErrorCode(err) != "NoSuchTagSet" {
+		return nil, fmt.Errorf("failed to get tags of S3 bucket %s: %w", bucketName, err)
+	}
+	tags := map[string]string{}
+	if taggingOutput != nil {
+		for _, tag := range taggingOutput.TagSet {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+	return tags, nil
+}
+
+// mergeBucketTags returns the tag set to write: the current tags without the previously
+// managed keys, plus the desired tags
+func mergeBucketTags(current, managed, desired map[string]string) map[string]string {
+	merged := make(map[string]string, len(current)+len(desired))
+	for key, value := range current {
+		if _, ok := managed[key]; ok {
+			continue
+		}
+		merged[key] = value
+	}
+	for key, value := range desired {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// dashWebsiteRegions use the older s3-website-<region> endpoint format. Every other region
+// uses s3-website.<region>.
+var dashWebsiteRegions = map[string]bool{
+	"us-east-1":      true,
+	"us-west-1":      true,
+	"us-west-2":      true,
+	"ap-southeast-1": true,
+	"ap-southeast-2": true,
+	"ap-northeast-1": true,
+	"eu-west-1":      true,
+	"sa-east-1":      true,
+	"us-gov-west-1":  true,
+}
+
+// applyBucketWebsite applies spec.website with PutBucketWebsite when it differs from what the
+// bucket has, and returns the website endpoint. When spec.website is removed, website hosting the operator turned on earlier
+// (websiteEnabled) is turned off again.
+func applyBucketWebsite(ctx context.Context, s3Client S3API, s3Bucket *computev1.S3Bucket, websiteEnabled bool) (string, error) {
+	l := logf.FromContext(ctx)
+	bucketName := s3Bucket.Spec.BucketName
+
+	if s3Bucket.Spec.Website == nil {
+		if websiteEnabled {
+			l.Info("Turning off S3 bucket website hosting", "bucketName", bucketName)
+			if _, err := s3Client.DeleteBucketWebsite(ctx, &s3.DeleteBucketWebsiteInput{
+				Bucket: aws.String(bucketName),
+			}); err != nil {
+				return "", fmt.Errorf("failed to delete website configuration of S3 bucket %s: %w", bucketName, err)
+			}
+		}
+		return "", nil
+	}
+
+	desired := buildWebsiteConfiguration(s3Bucket.Spec.Website)
+	current, err := getBucketWebsite(ctx, s3Client, bucketName)
+	if err != nil {
+		return "", err
+	}
+	if current != nil && reflect.DeepEqual(current, desired) {
+		return websiteEndpoint(bucketName, s3Bucket.Status.Region), nil
+	}
+
+	l.Info("Applying S3 bucket website configuration", "bucketName", bucketName)
+	if _, err := s3Client.PutBucketWebsite(ctx, &s3.PutBucketWebsiteInput{
+		Bucket:               aws.String(bucketName),
+		WebsiteConfiguration: desired,
+	}); err != nil {
+		if awsErrorCode(err) == "InvalidArgument" || awsErrorCode(err) == "MalformedXML" {
+			return "", newInvalidSpecError("website configuration rejected by S3: %v", err)
+		}
+		return "", fmt.Errorf("failed to put website configuration of S3 bucket %s: %w", bucketName, err)
+	}
+
+	return websiteEndpoint(bucketName, s3Bucket.Status.Region), nil
+}
+
+// getBucketWebsite returns the website configuration of the bucket in the form
+// buildWebsiteConfiguration produces, or nil if website hosting is off
+func getBucketWebsite(ctx context.Context, s3Client S3API, bucketName string) (*s3types.WebsiteConfiguration, error) {
+	output, err := s3Client.GetBucketWebsite(ctx, &s3.GetBucketWebsiteInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		if awsErrorCode(err) == "NoSuchWebsiteConfiguration" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get website configuration of S3 bucket %s: %w", bucketName, err)
+	}
+
+	config := &s3types.WebsiteConfiguration{
+		IndexDocument:         output.IndexDocument,
+		ErrorDocument:         output.ErrorDocument,
+		RedirectAllRequestsTo: output.RedirectAllRequestsTo,
+	}
+	if len(output.RoutingRules) > 0 {
+		config.RoutingRules = output.RoutingRules
+	}
+	return config, nil
+}
+
+// buildWebsiteConfiguration converts spec.website into an S3 website configuration
+func buildWebsiteConfiguration(website *computev1.WebsiteConfig) *s3types.WebsiteConfiguration {
+	config := &s3types.WebsiteConfiguration{}
+
+	if website.RedirectAllRequestsTo != nil {
+		config.RedirectAllRequestsTo = &s3types.RedirectAllRequestsTo{
+			HostName: aws.String(website.RedirectAllRequestsTo.HostName),
+			Protocol: s3types.Protocol(website.RedirectAllRequestsTo.Protocol),
+		}
+		return config
+	}
+
+	config.IndexDocument = &s3types.IndexDocument{
+		Suffix: aws.String(website.IndexDocument),
+	}
+	if website.ErrorDocument != "" {
+		config.ErrorDocument = &s3types.ErrorDocument{
+			Key: aws.String(website.ErrorDocument),
+		}
+	}
+	for _, specRule := range website.RoutingRules {
+		rule := s3types.RoutingRule{
+			Redirect: &s3types.Redirect{
+				HostName:             stringOrNil(specRule.Redirect.HostName),
+				Protocol:             s3types.Protocol(specRule.Redirect.Protocol),
+				ReplaceKeyPrefixWith: stringOrNil(specRule.Redirect.ReplaceKeyPrefixWith),
+				ReplaceKeyWith:       stringOrNil(specRule.Redirect.ReplaceKeyWith),
+				HttpRedirectCode:     stringOrNil(specRule.Redirect.HTTPRedirectCode),
+			},
+		}
+		if specRule.Condition != nil {
+			rule.Condition = &s3types.Condition{
+				KeyPrefixEquals:             stringOrNil(specRule.Condition.KeyPrefixEquals),
+				HttpErrorCodeReturnedEquals: stringOrNil(specRule.Condition.HTTPErrorCodeReturnedEquals),
+			}
+		}
+		config.RoutingRules = append(config.RoutingRules, rule)
+	}
+
+	return config
+}
+
+// websiteEndpoint returns the website URL of a bucket. Website endpoints only serve HTTP, and
+// the hostname format depends on the region.
+func websiteEndpoint(bucketName, region string) string {
+	domain := "amazonaws.com"
+	if strings.HasPrefix(region, "cn-") {
+		domain = "amazonaws.com.cn"
+	}
+	if dashWebsiteRegions[region] {
+		return fmt.Sprintf("http://%s.s3-website-%s.%s", bucketName, region, domain)
+	}
+	return fmt.Sprintf("http://%s.s3-website.%s.%s", bucketName, region, domain)
+}
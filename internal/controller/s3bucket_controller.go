@@ -18,8 +18,10 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -30,10 +32,35 @@ import (
 	computev1 "github.com/farhaan-shamsee/operator-repo/api/v1"
 )
 
+const (
+	// reasonBucketCreated is the Ready reason for a bucket that exists in AWS
+	reasonBucketCreated = "BucketCreated"
+	// reasonBucketNotEmpty is reported when deletion is blocked by objects left in the bucket
+	reasonBucketNotEmpty = "BucketNotEmpty"
+	// reasonEmptyingBucket is reported while spec.forceDelete empties the bucket
+	reasonEmptyingBucket = "EmptyingBucket"
+	// reasonDeletedOutOfBand is reported when the bucket was deleted outside the operator
+	reasonDeletedOutOfBand = "DeletedOutOfBand"
+	// reasonBucketDeleting is reported while AWS still finds a bucket it was asked to delete
+	reasonBucketDeleting = "BucketDeleting"
+
+	// s3ResyncInterval is how often an existing bucket is checked against AWS to detect drift
+	s3ResyncInterval = 5 * time.Minute
+
+	// bucketEmptyRequeueInterval paces the batches that empty a bucket for deletion
+	bucketEmptyRequeueInterval = 2 * time.Second
+	// bucketNotEmptyRetryInterval is how often deletion of a non-empty bucket is retried
+	bucketNotEmptyRetryInterval = time.Minute
+	// bucketDeletePollInterval is how often a deleted bucket is checked until AWS stops finding it
+	bucketDeletePollInterval = 5 * time.Second
+)
+
 // S3BucketReconciler reconciles a S3Bucket object
 type S3BucketReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// AWSClients creates the AWS clients used to manage the resources
+	AWSClients AWSClientProvider
 }
 
 // +kubebuilder:rbac:groups=compute.cloud.com,resources=s3buckets,verbs=get;list;watch;create;update;patch;delete
@@ -64,16 +91,54 @@ func (r *S3BucketReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 	}
 
+	// Send AWS calls to the account of the ProviderConfig, if the bucket names one
+	ctx, providerConfig, err := withProviderConfig(ctx, r.Client, s3bucket.Namespace, s3bucket.Spec.ProviderConfigRef)
+	if err == nil {
+		err = resolveRegion(&s3bucket.Status.Region, s3bucket.Spec.Region, providerConfig)
+	}
+	if err != nil && (s3bucket.DeletionTimestamp.IsZero() || s3bucket.Status.Created) {
+		// Deleting a created bucket waits too, it must not be looked for in the operator's own account
+		return r.providerConfigFailed(ctx, s3bucket, err)
+	}
+
 	if !s3bucket.DeletionTimestamp.IsZero() {
 		l.Info("Has deletion timestamp, bucket is being deleted")
 
-		if s3bucket.Status.Created {
+		if s3bucket.Status.Created && s3bucket.Spec.DeletionPolicy == computev1.DeletionPolicyRetain {
+			l.Info("Deletion policy is Retain, leaving S3 bucket in AWS", "BucketARN", s3bucket.Status.BucketARN)
+		} else if s3bucket.Status.Created {
+			if s3bucket.Spec.ForceDelete {
+				if result, done, err := r.emptyBucket(ctx, s3bucket); !done {
+					return result, err
+				}
+			}
+
 			l.Info("Deleting S3 bucket from AWS", "BucketARN", s3bucket.Status.BucketARN)
-			_, err := deleteS3Bucket(ctx, s3bucket)
+			deleted, err := deleteS3Bucket(ctx, r.AWSClients, s3bucket)
 			if err != nil {
+				if awsErrorCode(err) == "BucketNotEmpty" {
+					// Retrying quickly won't help until someone empties the bucket or sets forceDelete
+					l.Info("S3 bucket is not empty, waiting for it to be emptied", "BucketARN", s3bucket.Status.BucketARN)
+					setTerminalError(&s3bucket.Status.Conditions, s3bucket.Generation, reasonBucketNotEmpty,
+						"bucket still contains objects, empty it or set spec.forceDelete to delete them")
+					if err := r.Status().Update(ctx, s3bucket); err != nil {
+						l.Error(err, "Failed to update S3 bucket status")
+						return ctrl.Result{}, err
+					}
+					return ctrl.Result{RequeueAfter: bucketNotEmptyRetryInterval}, nil
+				}
 				l.Error(err, "Failed to delete S3 bucket from AWS", "BucketARN", s3bucket.Status.BucketARN)
 				return ctrl.Result{}, err
 			}
+			if !deleted {
+				setCondition(&s3bucket.Status.Conditions, s3bucket.Generation, computev1.ConditionReady, false,
+					reasonBucketDeleting, "bucket deletion requested, waiting for AWS to stop finding it")
+				if err := r.Status().Update(ctx, s3bucket); err != nil {
+					l.Error(err, "Failed to update S3 bucket status")
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{RequeueAfter: bucketDeletePollInterval}, nil
+			}
 		} else {
 			l.Info("Bucket was never created in AWS, skipping deletion")
 		}
@@ -93,16 +158,7 @@ func (r *S3BucketReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	}
 
 	if s3bucket.Status.BucketARN != "" {
-		l.Info("Bucket already exists in AWS, updating sync time", "BucketARN", s3bucket.Status.BucketARN)
-
-		// Update LastSyncTime to track when we last checked the bucket
-		s3bucket.Status.LastSyncTime = time.Now().Format(time.RFC3339)
-		if err := r.Status().Update(ctx, s3bucket); err != nil {
-			l.Error(err, "Failed to update LastSyncTime", "BucketARN", s3bucket.Status.BucketARN)
-			// Don't return error, this is not critical
-		}
-
-		return ctrl.Result{}, nil
+		return r.syncBucket(ctx, s3bucket)
 	}
 
 	if !controllerutil.ContainsFinalizer(s3bucket, "s3bucket.compute.cloud.com") {
@@ -119,17 +175,53 @@ func (r *S3BucketReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	l.Info("Creating new s3 bucket")
 
 	// Create new bucket
-	createdBucketInfo, err := createS3Bucket(ctx, s3bucket)
+	var createdBucketInfo *computev1.CreatedBucketInfo
+	if s3bucket.Spec.Adopt {
+		createdBucketInfo, err = adoptS3Bucket(ctx, r.AWSClients, s3bucket)
+	} else {
+		createdBucketInfo, err = createS3Bucket(ctx, r.AWSClients, s3bucket)
+	}
 	if err != nil {
+		if isInvalidSpec(err) {
+			l.Info("S3 bucket spec rejected", "reason", err.Error())
+			s3bucket.Status.ObservedGeneration = s3bucket.Generation
+			setTerminalError(&s3bucket.Status.Conditions, s3bucket.Generation, reasonInvalidSpec, err.Error())
+			if err := r.Status().Update(ctx, s3bucket); err != nil {
+				l.Error(err, "Failed to update S3 bucket status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+		if awsErrorCode(err) == "BucketAlreadyExists" {
+			// The name is owned by another account, retrying can never succeed
+			l.Info("S3 bucket name is already taken", "bucketName", s3bucket.Spec.BucketName)
+			s3bucket.Status.ObservedGeneration = s3bucket.Generation
+			setTerminalError(&s3bucket.Status.Conditions, s3bucket.Generation, "BucketNameTaken",
+				fmt.Sprintf("bucket name %s is already owned by another AWS account", s3bucket.Spec.BucketName))
+			if err := r.Status().Update(ctx, s3bucket); err != nil {
+				l.Error(err, "Failed to update S3 bucket status")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
 		l.Error(err, "Failed to create S3 bucket in AWS")
+		setSyncFailed(&s3bucket.Status.Conditions, s3bucket.Generation, reasonCreateFailed, err)
+		if err := r.Status().Update(ctx, s3bucket); err != nil {
+			l.Error(err, "Failed to record the creation failure in status")
+		}
 		return ctrl.Result{}, err
 	}
 
 	// Update status with created bucket info
 	s3bucket.Status.BucketARN = createdBucketInfo.BucketARN
 	s3bucket.Status.Created = true
+	s3bucket.Status.Adopted = s3bucket.Spec.Adopt
 	s3bucket.Status.Location = createdBucketInfo.Location
 	s3bucket.Status.LastSyncTime = time.Now().Format(time.RFC3339)
+
+	// Apply versioning and the other bucket settings now that the bucket exists
+	configErr := r.syncBucketConfiguration(ctx, s3bucket)
+
 	err = r.Status().Update(ctx, s3bucket)
 	if err != nil {
 		l.Error(err, "Failed to update S3 bucket status after creation", "BucketARN", s3bucket.Status.BucketARN)
@@ -137,7 +229,181 @@ func (r *S3BucketReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	}
 
 	l.Info("S3 bucket created and status updated successfully", "BucketARN", s3bucket.Status.BucketARN)
-	return ctrl.Result{}, nil
+	if configErr != nil {
+		return ctrl.Result{}, configErr
+	}
+	return ctrl.Result{RequeueAfter: s3ResyncInterval}, nil
+}
+
+// syncBucket checks an existing bucket against AWS every s3ResyncInterval, or right away when
+// the spec or labels changed. Drift is recorded in status.drift and corrected by applying the
+// bucket configuration again. LastSyncTime only moves when AWS was actually checked.
+func (r *S3BucketReconciler) syncBucket(ctx context.Context, s3bucket *computev1.S3Bucket) (ctrl.Result, error) {
+	l := logf.FromContext(ctx)
+
+	if !s3bucket.Status.Created {
+		l.Info("Bucket was deleted outside of the operator, nothing left to sync", "BucketARN", s3bucket.Status.BucketARN)
+		return ctrl.Result{}, nil
+	}
+
+	if wait := bucketResyncWait(s3bucket, time.Now()); wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	l.Info("Checking S3 bucket for drift", "BucketARN", s3bucket.Status.BucketARN)
+	original := s3bucket.Status.DeepCopy()
+	exists, drift, err := describeS3Bucket(ctx, r.AWSClients, s3bucket)
+	if err != nil && !isInvalidSpec(err) {
+		l.Error(err, "Failed to describe S3 bucket", "BucketARN", s3bucket.Status.BucketARN)
+		setSyncFailed(&s3bucket.Status.Conditions, s3bucket.Generation, reasonSyncFailed, err)
+		if err := r.Status().Update(ctx, s3bucket); err != nil {
+			l.Error(err, "Failed to record the sync failure in status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	var configErr error
+	switch {
+	case !exists:
+		l.Info("S3 bucket was deleted outside of the operator", "BucketARN", s3bucket.Status.BucketARN)
+		s3bucket.Status.Created = false
+		s3bucket.Status.Drift = nil
+		setTerminalError(&s3bucket.Status.Conditions, s3bucket.Generation, reasonDeletedOutOfBand,
+			"bucket no longer exists in AWS, it was deleted outside of the operator")
+	case err != nil:
+		setBucketSpecRejected(ctx, s3bucket, err)
+	default:
+		// Differences right after a spec change are the change itself, not drift
+		s3bucket.Status.Drift = nil
+		if s3bucket.Status.ObservedGeneration == s3bucket.Generation && len(drift) > 0 {
+			l.Info("S3 bucket drifted from the spec, correcting it", "BucketARN", s3bucket.Status.BucketARN, "drift", drift)
+			s3bucket.Status.Drift = drift
+		}
+		configErr = r.syncBucketConfiguration(ctx, s3bucket)
+	}
+	// A failed sync keeps the previous LastSyncTime, so retries back off instead of
+	// triggering each other through status updates
+	if configErr == nil {
+		s3bucket.Status.LastSyncTime = time.Now().Format(time.RFC3339)
+	}
+
+	if !equality.Semantic.DeepEqual(original, &s3bucket.Status) {
+		if err := r.Status().Update(ctx, s3bucket); err != nil {
+			l.Error(err, "Failed to update S3 bucket status", "BucketARN", s3bucket.Status.BucketARN)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if configErr != nil {
+		return ctrl.Result{}, configErr
+	}
+	if !s3bucket.Status.Created {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: s3ResyncInterval}, nil
+}
+
+// bucketResyncWait returns how long until the bucket is due to be checked against AWS again,
+// or 0 if it is due now. A spec change makes it due right away; label changes are picked up
+// by the next resync. Without this, the status update of every sync would trigger the next one.
+func bucketResyncWait(s3bucket *computev1.S3Bucket, now time.Time) time.Duration {
+	if s3bucket.Status.ObservedGeneration != s3bucket.Generation {
+		return 0
+	}
+	lastSync, err := time.Parse(time.RFC3339, s3bucket.Status.LastSyncTime)
+	if err != nil {
+		return 0
+	}
+	return max(s3ResyncInterval-now.Sub(lastSync), 0)
+}
+
+// providerConfigFailed records why spec.providerConfigRef or the region it provides can't be
+// used. Invalid references are checked again every s3ResyncInterval, so creating the missing
+// ProviderConfig or allowing the namespace later is picked up.
+func (r *S3BucketReconciler) providerConfigFailed(ctx context.Context, s3bucket *computev1.S3Bucket, err error) (ctrl.Result, error) {
+	l := logf.FromContext(ctx)
+
+	if !isInvalidSpec(err) {
+		l.Error(err, "Failed to resolve the providerConfig")
+		return ctrl.Result{}, err
+	}
+
+	l.Info("S3 bucket providerConfig rejected", "reason", err.Error())
+	setTerminalError(&s3bucket.Status.Conditions, s3bucket.Generation, reasonProviderConfigInvalid, err.Error())
+	if err := r.Status().Update(ctx, s3bucket); err != nil {
+		l.Error(err, "Failed to update S3 bucket status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: s3ResyncInterval}, nil
+}
+
+// emptyBucket deletes a batch of object versions and reports progress in status. It returns
+// done once the bucket is empty; until then the caller should return the result and error.
+func (r *S3BucketReconciler) emptyBucket(ctx context.Context, s3bucket *computev1.S3Bucket) (ctrl.Result, bool, error) {
+	l := logf.FromContext(ctx)
+
+	deleted, empty, err := emptyS3Bucket(ctx, r.AWSClients, s3bucket)
+	s3bucket.Status.ObjectsDeleted += deleted
+	if err != nil {
+		l.Error(err, "Failed to empty S3 bucket", "BucketARN", s3bucket.Status.BucketARN)
+		setSyncFailed(&s3bucket.Status.Conditions, s3bucket.Generation, reasonEmptyingBucket, err)
+		if err := r.Status().Update(ctx, s3bucket); err != nil {
+			l.Error(err, "Failed to record the emptying failure in status")
+		}
+		return ctrl.Result{}, false, err
+	}
+	if empty {
+		return ctrl.Result{}, true, nil
+	}
+
+	setCondition(&s3bucket.Status.Conditions, s3bucket.Generation, computev1.ConditionReady, false, reasonEmptyingBucket,
+		fmt.Sprintf("deleted %d object versions so far", s3bucket.Status.ObjectsDeleted))
+	if err := r.Status().Update(ctx, s3bucket); err != nil {
+		l.Error(err, "Failed to update S3 bucket status")
+		return ctrl.Result{}, false, err
+	}
+	return ctrl.Result{RequeueAfter: bucketEmptyRequeueInterval}, false, nil
+}
+
+// syncBucketConfiguration applies the bucket settings from the spec and records the outcome
+// in status and conditions, without writing the status. It returns the errors worth retrying.
+func (r *S3BucketReconciler) syncBucketConfiguration(ctx context.Context, s3bucket *computev1.S3Bucket) error {
+	l := logf.FromContext(ctx)
+
+	configInfo, err := configureS3Bucket(ctx, r.AWSClients, s3bucket)
+	if err != nil {
+		if isInvalidSpec(err) {
+			setBucketSpecRejected(ctx, s3bucket, err)
+			return nil
+		}
+		l.Error(err, "Failed to apply S3 bucket configuration", "BucketARN", s3bucket.Status.BucketARN)
+		setCondition(&s3bucket.Status.Conditions, s3bucket.Generation, computev1.ConditionReady, true, reasonBucketCreated, "")
+		setSyncFailed(&s3bucket.Status.Conditions, s3bucket.Generation, "ConfigureFailed", err)
+		return err
+	}
+
+	s3bucket.Status.Versioning = configInfo.Versioning
+	s3bucket.Status.LifecycleRules = configInfo.LifecycleRules
+	s3bucket.Status.CORSRules = configInfo.CORSRules
+	s3bucket.Status.WebsiteEndpoint = configInfo.WebsiteEndpoint
+	s3bucket.Status.Encryption = configInfo.Encryption
+	s3bucket.Status.ObjectOwnership = configInfo.ObjectOwnership
+	s3bucket.Status.PublicAccessBlock = configInfo.PublicAccessBlock
+	s3bucket.Status.PolicyApplied = configInfo.PolicyApplied
+	s3bucket.Status.Tags = configInfo.Tags
+	s3bucket.Status.ObservedGeneration = s3bucket.Generation
+	setSynced(&s3bucket.Status.Conditions, s3bucket.Generation, true, reasonBucketCreated, "")
+	return nil
+}
+
+// setBucketSpecRejected records a spec that can't be applied to an existing bucket. The bucket
+// itself is fine, but it can't be synced until the spec is fixed.
+func setBucketSpecRejected(ctx context.Context, s3bucket *computev1.S3Bucket, err error) {
+	logf.FromContext(ctx).Info("S3 bucket spec rejected", "reason", err.Error())
+	s3bucket.Status.ObservedGeneration = s3bucket.Generation
+	setCondition(&s3bucket.Status.Conditions, s3bucket.Generation, computev1.ConditionReady, true, reasonBucketCreated, "")
+	setCondition(&s3bucket.Status.Conditions, s3bucket.Generation, computev1.ConditionSynced, false, reasonInvalidSpec, err.Error())
+	setCondition(&s3bucket.Status.Conditions, s3bucket.Generation, computev1.ConditionError, true, reasonInvalidSpec, err.Error())
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -18,11 +18,21 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -51,7 +61,11 @@ var _ = Describe("S3Bucket Controller", func() {
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					// Either a region or a providerConfigRef is required
+					Spec: computev1.S3BucketSpec{
+						BucketName: "operator-test-bucket",
+						Region:     "ap-south-1",
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
@@ -69,8 +83,9 @@ var _ = Describe("S3Bucket Controller", func() {
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &S3BucketReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				AWSClients: newFakeAWS(),
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -81,4 +96,756 @@ var _ = Describe("S3Bucket Controller", func() {
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
 	})
+
+	Context("When managing a bucket against the fake AWS backend", func() {
+		const resourceName = "lifecycle-bucket"
+		const bucketName = "operator-lifecycle-bucket"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		var fakeAWS *fakeAWS
+		var controllerReconciler *S3BucketReconciler
+
+		reconcileOnce := func() error {
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			return err
+		}
+		getBucket := func() *computev1.S3Bucket {
+			s3bucket := &computev1.S3Bucket{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, s3bucket)).To(Succeed())
+			return s3bucket
+		}
+
+		BeforeEach(func() {
+			fakeAWS = newFakeAWS()
+			controllerReconciler = &S3BucketReconciler{
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				AWSClients: fakeAWS,
+			}
+
+			Expect(k8sClient.Create(ctx, &computev1.S3Bucket{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: computev1.S3BucketSpec{
+					BucketName: bucketName,
+					Region:     "ap-south-1",
+					Tags:       map[string]string{"team": "storage"},
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			s3bucket := &computev1.S3Bucket{}
+			if err := k8sClient.Get(ctx, typeNamespacedName, s3bucket); errors.IsNotFound(err) {
+				return
+			}
+			controllerutil.RemoveFinalizer(s3bucket, "s3bucket.compute.cloud.com")
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, s3bucket))).To(Succeed())
+		})
+
+		It("should create, update, correct drift and force delete the bucket", func() {
+			By("adding the finalizer")
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Finalizers).To(ContainElement("s3bucket.compute.cloud.com"))
+
+			By("creating the bucket, retrying after a failed CreateBucket")
+			fakeAWS.failNext("CreateBucket", apiError("ServiceUnavailable", "Please reduce your request rate."))
+			Expect(reconcileOnce()).NotTo(Succeed())
+			Expect(findCondition(getBucket().Status.Conditions, computev1.ConditionSynced).Reason).To(Equal(reasonCreateFailed))
+
+			Expect(reconcileOnce()).To(Succeed())
+			s3bucket := getBucket()
+			Expect(s3bucket.Status.Created).To(BeTrue())
+			Expect(s3bucket.Status.BucketARN).To(Equal("arn:aws:s3:::" + bucketName))
+			Expect(s3bucket.Status.Tags).To(HaveKeyWithValue("team", "storage"))
+			Expect(findCondition(s3bucket.Status.Conditions, computev1.ConditionSynced).Status).To(Equal("True"))
+
+			bucket, found := fakeAWS.bucket(bucketName)
+			Expect(found).To(BeTrue())
+			Expect(bucket.region).To(Equal("ap-south-1"))
+
+			By("enabling versioning when the spec changes")
+			s3bucket.Spec.Versioning = "Enabled"
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Status.Versioning).To(Equal("Enabled"))
+			Expect(bucket.versioning).To(Equal(s3types.BucketVersioningStatusEnabled))
+
+			By("correcting versioning suspended outside of the operator")
+			s3Client, err := fakeAWS.S3(ctx, "ap-south-1")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s3Client.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+				Bucket:                  aws.String(bucketName),
+				VersioningConfiguration: &s3types.VersioningConfiguration{Status: s3types.BucketVersioningStatusSuspended},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Make the bucket due for its periodic check
+			s3bucket = getBucket()
+			s3bucket.Status.LastSyncTime = time.Now().Add(-2 * s3ResyncInterval).Format(time.RFC3339)
+			Expect(k8sClient.Status().Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Status.Drift).To(ConsistOf(driftVersioning))
+			Expect(bucket.versioning).To(Equal(s3types.BucketVersioningStatusEnabled))
+
+			By("emptying and deleting the bucket when the resource is deleted")
+			for i := range 3 {
+				fakeAWS.putObject(bucketName, fmt.Sprintf("reports/%d.csv", i))
+			}
+			fakeAWS.putObject(bucketName, "reports/0.csv")
+
+			s3bucket = getBucket()
+			s3bucket.Spec.ForceDelete = true
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, s3bucket)).To(Succeed())
+			Eventually(func() error {
+				if err := reconcileOnce(); err != nil {
+					return err
+				}
+				return k8sClient.Get(ctx, typeNamespacedName, &computev1.S3Bucket{})
+			}).Should(Satisfy(errors.IsNotFound))
+
+			_, found = fakeAWS.bucket(bucketName)
+			Expect(found).To(BeFalse())
+			Expect(fakeAWS.callsTo("DeleteObjects")).To(Equal(1))
+		})
+
+		It("should recognise a bucket it created before recording it in status", func() {
+			Expect(reconcileOnce()).To(Succeed())
+			s3bucket := getBucket()
+
+			// The bucket was created and tagged, but the status update that followed failed
+			s3Client, err := fakeAWS.S3(ctx, "ap-south-1")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s3Client.CreateBucket(ctx, &s3.CreateBucketInput{
+				Bucket: aws.String(bucketName),
+				CreateBucketConfiguration: &s3types.CreateBucketConfiguration{
+					LocationConstraint: s3types.BucketLocationConstraintApSouth1,
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s3Client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+				Bucket: aws.String(bucketName),
+				Tagging: &s3types.Tagging{TagSet: []s3types.Tag{{
+					Key:   aws.String(ownerUIDTagKey),
+					Value: aws.String(string(s3bucket.UID)),
+				}}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(reconcileOnce()).To(Succeed())
+			s3bucket = getBucket()
+			Expect(s3bucket.Status.Created).To(BeTrue())
+			Expect(findCondition(s3bucket.Status.Conditions, computev1.ConditionSynced).Status).To(Equal("True"))
+		})
+
+		It("should refuse a bucket that belongs to another resource", func() {
+			Expect(reconcileOnce()).To(Succeed())
+
+			s3Client, err := fakeAWS.S3(ctx, "ap-south-1")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s3Client.CreateBucket(ctx, &s3.CreateBucketInput{
+				Bucket: aws.String(bucketName),
+				CreateBucketConfiguration: &s3types.CreateBucketConfiguration{
+					LocationConstraint: s3types.BucketLocationConstraintApSouth1,
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(reconcileOnce()).To(Succeed())
+			s3bucket := getBucket()
+			Expect(s3bucket.Status.Created).To(BeFalse())
+			Expect(findCondition(s3bucket.Status.Conditions, computev1.ConditionReady).Reason).To(Equal(reasonInvalidSpec))
+		})
+
+		It("should wait for a bucket that is not empty", func() {
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			fakeAWS.putObject(bucketName, "keep.txt")
+
+			Expect(k8sClient.Delete(ctx, getBucket())).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			s3bucket := getBucket()
+			Expect(s3bucket.DeletionTimestamp).NotTo(BeNil())
+			Expect(findCondition(s3bucket.Status.Conditions, computev1.ConditionReady).Reason).To(Equal(reasonBucketNotEmpty))
+
+			_, found := fakeAWS.bucket(bucketName)
+			Expect(found).To(BeTrue())
+		})
+
+		It("should adopt an untagged bucket and keep its existing tags", func() {
+			s3Client, err := fakeAWS.S3(ctx, "ap-south-1")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s3Client.CreateBucket(ctx, &s3.CreateBucketInput{
+				Bucket: aws.String(bucketName),
+				CreateBucketConfiguration: &s3types.CreateBucketConfiguration{
+					LocationConstraint: s3types.BucketLocationConstraintApSouth1,
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s3Client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+				Bucket:  aws.String(bucketName),
+				Tagging: &s3types.Tagging{TagSet: []s3types.Tag{{Key: aws.String("cost-center"), Value: aws.String("42")}}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			s3bucket := getBucket()
+			s3bucket.Spec.Adopt = true
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+
+			s3bucket = getBucket()
+			Expect(s3bucket.Status.Adopted).To(BeTrue())
+			Expect(s3bucket.Status.Created).To(BeTrue())
+			Expect(fakeAWS.callsTo("CreateBucket")).To(Equal(1))
+
+			bucket, _ := fakeAWS.bucket(bucketName)
+			Expect(bucket.tags).To(ContainElements(
+				s3types.Tag{Key: aws.String(ownerUIDTagKey), Value: aws.String(string(s3bucket.UID))},
+				s3types.Tag{Key: aws.String("cost-center"), Value: aws.String("42")},
+			))
+		})
+
+		It("should refuse to adopt a bucket that belongs to another resource", func() {
+			s3Client, err := fakeAWS.S3(ctx, "ap-south-1")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s3Client.CreateBucket(ctx, &s3.CreateBucketInput{
+				Bucket: aws.String(bucketName),
+				CreateBucketConfiguration: &s3types.CreateBucketConfiguration{
+					LocationConstraint: s3types.BucketLocationConstraintApSouth1,
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			foreignTags := []s3types.Tag{{Key: aws.String(ownerUIDTagKey), Value: aws.String("another-uid")}}
+			_, err = s3Client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+				Bucket:  aws.String(bucketName),
+				Tagging: &s3types.Tagging{TagSet: foreignTags},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			s3bucket := getBucket()
+			s3bucket.Spec.Adopt = true
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+
+			s3bucket = getBucket()
+			Expect(s3bucket.Status.Created).To(BeFalse())
+			Expect(findCondition(s3bucket.Status.Conditions, computev1.ConditionError).Reason).To(Equal(reasonInvalidSpec))
+			Expect(findCondition(s3bucket.Status.Conditions, computev1.ConditionError).Message).To(ContainSubstring("another-uid"))
+
+			bucket, _ := fakeAWS.bucket(bucketName)
+			Expect(bucket.tags).To(Equal(foreignTags))
+		})
+
+		It("should report a bucket to adopt that does not exist", func() {
+			s3bucket := getBucket()
+			s3bucket.Spec.Adopt = true
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+
+			s3bucket = getBucket()
+			Expect(s3bucket.Status.Created).To(BeFalse())
+			Expect(findCondition(s3bucket.Status.Conditions, computev1.ConditionError).Reason).To(Equal(reasonInvalidSpec))
+			Expect(findCondition(s3bucket.Status.Conditions, computev1.ConditionError).Message).To(ContainSubstring("does not exist"))
+			Expect(fakeAWS.callsTo("CreateBucket")).To(BeZero())
+		})
+
+		It("should apply Enabled and Suspended versioning and leave a never versioned bucket alone", func() {
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			s3bucket := getBucket()
+			Expect(s3bucket.Status.Created).To(BeTrue())
+			Expect(s3bucket.Status.Versioning).To(Equal(versioningDisabled))
+			Expect(fakeAWS.callsTo("PutBucketVersioning")).To(BeZero())
+
+			bucket, _ := fakeAWS.bucket(bucketName)
+			Expect(bucket.versioning).To(BeEmpty())
+
+			By("enabling versioning")
+			s3bucket.Spec.Versioning = "Enabled"
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Status.Versioning).To(Equal("Enabled"))
+			Expect(bucket.versioning).To(Equal(s3types.BucketVersioningStatusEnabled))
+			Expect(fakeAWS.callsTo("PutBucketVersioning")).To(Equal(1))
+
+			By("suspending versioning")
+			s3bucket = getBucket()
+			s3bucket.Spec.Versioning = "Suspended"
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Status.Versioning).To(Equal("Suspended"))
+			Expect(bucket.versioning).To(Equal(s3types.BucketVersioningStatusSuspended))
+			Expect(fakeAWS.callsTo("PutBucketVersioning")).To(Equal(2))
+
+			By("not calling PutBucketVersioning again when nothing changed")
+			s3bucket = getBucket()
+			s3bucket.Status.LastSyncTime = time.Now().Add(-2 * s3ResyncInterval).Format(time.RFC3339)
+			Expect(k8sClient.Status().Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Status.Drift).To(BeEmpty())
+			Expect(fakeAWS.callsTo("PutBucketVersioning")).To(Equal(2))
+		})
+
+		It("should only put lifecycle rules that differ from the bucket's", func() {
+			s3bucket := getBucket()
+			s3bucket.Spec.StorageClass = "GLACIER"
+			s3bucket.Spec.LifecycleRules = []computev1.LifecycleRule{{ID: "logs", Prefix: "logs/", ExpirationDays: 90}}
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Status.LifecycleRules).To(Equal(int32(2)))
+			Expect(fakeAWS.callsTo("PutBucketLifecycleConfiguration")).To(Equal(1))
+
+			resync := func() {
+				s3bucket := getBucket()
+				s3bucket.Status.LastSyncTime = time.Now().Add(-2 * s3ResyncInterval).Format(time.RFC3339)
+				Expect(k8sClient.Status().Update(ctx, s3bucket)).To(Succeed())
+				Expect(reconcileOnce()).To(Succeed())
+			}
+
+			By("leaving unchanged rules alone, even when S3 returns them in another order")
+			bucket, _ := fakeAWS.bucket(bucketName)
+			slices.Reverse(bucket.lifecycle)
+			resync()
+			Expect(fakeAWS.callsTo("PutBucketLifecycleConfiguration")).To(Equal(1))
+
+			By("putting the rules back after they were changed outside of the operator")
+			s3Client, err := fakeAWS.S3(ctx, "ap-south-1")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s3Client.DeleteBucketLifecycle(ctx, &s3.DeleteBucketLifecycleInput{Bucket: aws.String(bucketName)})
+			Expect(err).NotTo(HaveOccurred())
+			resync()
+			Expect(fakeAWS.callsTo("PutBucketLifecycleConfiguration")).To(Equal(2))
+			Expect(bucket.lifecycle).To(HaveLen(2))
+		})
+
+		It("should apply SSE-S3 default encryption only when the bucket lacks it", func() {
+			s3bucket := getBucket()
+			s3bucket.Spec.Encryption = &computev1.BucketEncryption{Algorithm: "AES256"}
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+
+			By("keeping the SSE-S3 default S3 gives new buckets")
+			Expect(getBucket().Status.Encryption).To(Equal(&computev1.BucketEncryptionStatus{Algorithm: "AES256"}))
+			Expect(fakeAWS.callsTo("PutBucketEncryption")).To(BeZero())
+
+			By("switching to SSE-KMS and back")
+			s3bucket = getBucket()
+			s3bucket.Spec.Encryption = &computev1.BucketEncryption{Algorithm: "aws:kms"}
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Status.Encryption).To(Equal(&computev1.BucketEncryptionStatus{Algorithm: "aws:kms"}))
+
+			s3bucket = getBucket()
+			s3bucket.Spec.Encryption = &computev1.BucketEncryption{Algorithm: "AES256"}
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Status.Encryption).To(Equal(&computev1.BucketEncryptionStatus{Algorithm: "AES256"}))
+
+			bucket, _ := fakeAWS.bucket(bucketName)
+			Expect(bucket.encryption.Rules).To(HaveLen(1))
+			Expect(bucket.encryption.Rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm).To(Equal(s3types.ServerSideEncryptionAes256))
+			Expect(bucket.encryption.Rules[0].ApplyServerSideEncryptionByDefault.KMSMasterKeyID).To(BeNil())
+			Expect(fakeAWS.callsTo("PutBucketEncryption")).To(Equal(2))
+		})
+
+		It("should apply SSE-KMS with a key and correct encryption changed outside of the operator", func() {
+			const keyID = "arn:aws:kms:ap-south-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+			desired := &computev1.BucketEncryptionStatus{Algorithm: "aws:kms", KMSKeyID: keyID, BucketKeyEnabled: true}
+
+			s3bucket := getBucket()
+			s3bucket.Spec.Encryption = &computev1.BucketEncryption{Algorithm: "aws:kms", KMSKeyID: keyID, BucketKeyEnabled: true}
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+
+			Expect(getBucket().Status.Encryption).To(Equal(desired))
+			bucket, _ := fakeAWS.bucket(bucketName)
+			rule := bucket.encryption.Rules[0]
+			Expect(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm).To(Equal(s3types.ServerSideEncryptionAwsKms))
+			Expect(aws.ToString(rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID)).To(Equal(keyID))
+			Expect(aws.ToBool(rule.BucketKeyEnabled)).To(BeTrue())
+			Expect(fakeAWS.callsTo("PutBucketEncryption")).To(Equal(1))
+
+			resync := func() {
+				s3bucket := getBucket()
+				s3bucket.Status.LastSyncTime = time.Now().Add(-2 * s3ResyncInterval).Format(time.RFC3339)
+				Expect(k8sClient.Status().Update(ctx, s3bucket)).To(Succeed())
+				Expect(reconcileOnce()).To(Succeed())
+			}
+
+			By("not calling PutBucketEncryption when nothing changed")
+			resync()
+			Expect(getBucket().Status.Drift).To(BeEmpty())
+			Expect(fakeAWS.callsTo("PutBucketEncryption")).To(Equal(1))
+
+			By("switching back to the spec after SSE-S3 was set outside of the operator")
+			s3Client, err := fakeAWS.S3(ctx, "ap-south-1")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s3Client.PutBucketEncryption(ctx, &s3.PutBucketEncryptionInput{
+				Bucket: aws.String(bucketName),
+				ServerSideEncryptionConfiguration: &s3types.ServerSideEncryptionConfiguration{
+					Rules: []s3types.ServerSideEncryptionRule{{
+						ApplyServerSideEncryptionByDefault: &s3types.ServerSideEncryptionByDefault{
+							SSEAlgorithm: s3types.ServerSideEncryptionAes256,
+						},
+					}},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			resync()
+
+			s3bucket = getBucket()
+			Expect(s3bucket.Status.Drift).To(ConsistOf(driftEncryption))
+			Expect(s3bucket.Status.Encryption).To(Equal(desired))
+			bucket, _ = fakeAWS.bucket(bucketName)
+			Expect(aws.ToString(bucket.encryption.Rules[0].ApplyServerSideEncryptionByDefault.KMSMasterKeyID)).To(Equal(keyID))
+			Expect(fakeAWS.callsTo("PutBucketEncryption")).To(Equal(3))
+		})
+
+		It("should not put a policy again when S3 returns it normalized", func() {
+			s3bucket := getBucket()
+			s3bucket.Spec.Policy = &computev1.BucketPolicy{
+				Statements: []computev1.PolicyStatement{{
+					Principals: []string{"arn:aws:iam::123456789012:role/reader"},
+					Actions:    []string{"s3:GetObject"},
+				}},
+			}
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Status.PolicyApplied).To(BeTrue())
+			Expect(fakeAWS.callsTo("PutBucketPolicy")).To(Equal(1))
+
+			// S3 stores the policy in its own form
+			bucket, _ := fakeAWS.bucket(bucketName)
+			bucket.policy = aws.String(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow",` +
+				`"Principal":{"AWS":"arn:aws:iam::123456789012:role/reader"},"Action":"s3:GetObject",` +
+				`"Resource":["arn:aws:s3:::` + bucketName + `/*","arn:aws:s3:::` + bucketName + `"]}]}`)
+
+			s3bucket = getBucket()
+			s3bucket.Status.LastSyncTime = time.Now().Add(-2 * s3ResyncInterval).Format(time.RFC3339)
+			Expect(k8sClient.Status().Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(fakeAWS.callsTo("PutBucketPolicy")).To(Equal(1))
+		})
+
+		It("should requeue instead of waiting while a deleted bucket is still visible", func() {
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Status.Created).To(BeTrue())
+
+			fakeAWS.lingerAfterDelete(bucketName, 1)
+			Expect(k8sClient.Delete(ctx, getBucket())).To(Succeed())
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(bucketDeletePollInterval))
+
+			s3bucket := getBucket()
+			Expect(s3bucket.Finalizers).To(ContainElement("s3bucket.compute.cloud.com"))
+			Expect(findCondition(s3bucket.Status.Conditions, computev1.ConditionReady).Reason).To(Equal(reasonBucketDeleting))
+
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &computev1.S3Bucket{})).To(Satisfy(errors.IsNotFound))
+		})
+
+		It("should only put CORS rules that differ from the bucket's", func() {
+			s3bucket := getBucket()
+			s3bucket.Spec.CORSRules = []computev1.CORSRule{{
+				AllowedOrigins: []string{"https://example.com"},
+				AllowedMethods: []string{"GET", "HEAD"},
+				MaxAgeSeconds:  3000,
+			}}
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Status.CORSRules).To(Equal(int32(1)))
+			Expect(fakeAWS.callsTo("PutBucketCors")).To(Equal(1))
+
+			resync := func() {
+				s3bucket := getBucket()
+				s3bucket.Status.LastSyncTime = time.Now().Add(-2 * s3ResyncInterval).Format(time.RFC3339)
+				Expect(k8sClient.Status().Update(ctx, s3bucket)).To(Succeed())
+				Expect(reconcileOnce()).To(Succeed())
+			}
+
+			By("leaving unchanged rules alone")
+			resync()
+			Expect(fakeAWS.callsTo("PutBucketCors")).To(Equal(1))
+
+			By("putting the rules back after they were changed outside of the operator")
+			bucket, _ := fakeAWS.bucket(bucketName)
+			bucket.cors[0].AllowedOrigins = []string{"*"}
+			resync()
+			Expect(fakeAWS.callsTo("PutBucketCors")).To(Equal(2))
+			Expect(bucket.cors[0].AllowedOrigins).To(Equal([]string{"https://example.com"}))
+		})
+
+		It("should only put a website configuration that differs from the bucket's", func() {
+			s3bucket := getBucket()
+			s3bucket.Spec.Website = &computev1.WebsiteConfig{
+				IndexDocument: "index.html",
+				ErrorDocument: "error.html",
+				RoutingRules: []computev1.RoutingRule{{
+					Condition: &computev1.RoutingRuleCondition{KeyPrefixEquals: "docs/"},
+					Redirect:  computev1.RoutingRuleRedirect{ReplaceKeyPrefixWith: "documents/"},
+				}},
+			}
+			Expect(k8sClient.Update(ctx, s3bucket)).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(reconcileOnce()).To(Succeed())
+			Expect(getBucket().Status.WebsiteEndpoint).To(Equal(websiteEndpoint(bucketName, "ap-south-1")))
+			Expect(fakeAWS.callsTo("PutBucketWebsite")).To(Equal(1))
+
+			resync := func() {
+				s3bucket := getBucket()
+				s3bucket.Status.LastSyncTime = time.Now().Add(-2 * s3ResyncInterval).Format(time.RFC3339)
+				Expect(k8sClient.Status().Update(ctx, s3bucket)).To(Succeed())
+				Expect(reconcileOnce()).To(Succeed())
+			}
+
+			By("leaving an unchanged configuration alone")
+			resync()
+			Expect(fakeAWS.callsTo("PutBucketWebsite")).To(Equal(1))
+
+			By("putting the configuration back after it was changed outside of the operator")
+			bucket, _ := fakeAWS.bucket(bucketName)
+			bucket.website.ErrorDocument = &s3types.ErrorDocument{Key: aws.String("404.html")}
+			resync()
+			Expect(fakeAWS.callsTo("PutBucketWebsite")).To(Equal(2))
+			Expect(aws.ToString(bucket.website.ErrorDocument.Key)).To(Equal("error.html"))
+		})
+	})
+
+	Context("When building lifecycle rules", func() {
+		It("should map the storage class onto a transition rule ahead of the spec rules", func() {
+			s3bucket := &computev1.S3Bucket{
+				Spec: computev1.S3BucketSpec{
+					StorageClass: "GLACIER",
+					LifecycleRules: []computev1.LifecycleRule{
+						{Prefix: "logs/", ExpirationDays: 90},
+					},
+				},
+			}
+
+			rules, err := buildLifecycleRules(s3bucket)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rules).To(HaveLen(2))
+			Expect(*rules[0].ID).To(Equal(storageClassRuleID))
+			Expect(string(rules[0].Transitions[0].StorageClass)).To(Equal("GLACIER"))
+			Expect(*rules[1].ID).To(Equal("rule-0"))
+			Expect(*rules[1].Filter.Prefix).To(Equal("logs/"))
+			Expect(*rules[1].Expiration.Days).To(Equal(int32(90)))
+		})
+
+		It("should reject storage classes S3 cannot transition to", func() {
+			s3bucket := &computev1.S3Bucket{
+				Spec: computev1.S3BucketSpec{StorageClass: "REDUCED_REDUNDANCY"},
+			}
+
+			_, err := buildLifecycleRules(s3bucket)
+			Expect(isInvalidSpec(err)).To(BeTrue())
+		})
+	})
+
+	Context("When validating bucket access", func() {
+		It("should block public access unless flags are opted out", func() {
+			optOut := false
+			block := publicAccessBlockFromSpec(computev1.PublicAccessBlockConfig{BlockPublicAcls: &optOut})
+			Expect(block.BlockPublicAcls).To(BeFalse())
+			Expect(block.IgnorePublicAcls).To(BeTrue())
+			Expect(block.BlockPublicPolicy).To(BeTrue())
+			Expect(block.RestrictPublicBuckets).To(BeTrue())
+		})
+
+		It("should reject ACLs S3 would refuse", func() {
+			blocked := publicAccessBlockFromSpec(computev1.PublicAccessBlockConfig{})
+			Expect(validateBucketAccess("private", "BucketOwnerEnforced", blocked)).To(Succeed())
+			Expect(isInvalidSpec(validateBucketAccess("public-read", "BucketOwnerEnforced", blocked))).To(BeTrue())
+			Expect(isInvalidSpec(validateBucketAccess("public-read", "BucketOwnerPreferred", blocked))).To(BeTrue())
+			Expect(validateBucketAccess("authenticated-read", "ObjectWriter", blocked)).To(Succeed())
+		})
+	})
+
+	Context("When building a bucket policy", func() {
+		It("should scope structured statements to the bucket and prefixes", func() {
+			policy, err := buildBucketPolicy("reports-bucket", &computev1.BucketPolicy{
+				Statements: []computev1.PolicyStatement{
+					{
+						Principals:       []string{"arn:aws:iam::123456789012:role/reader"},
+						Actions:          []string{"s3:GetObject"},
+						ResourcePrefixes: []string{"reports/"},
+					},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy).To(MatchJSON(`{
+				"Version": "2012-10-17",
+				"Statement": [{
+					"Effect": "Allow",
+					"Principal": {"AWS": ["arn:aws:iam::123456789012:role/reader"]},
+					"Action": ["s3:GetObject"],
+					"Resource": ["arn:aws:s3:::reports-bucket/reports/*"]
+				}]
+			}`))
+		})
+
+		It("should match the policy S3 returns after normalizing it", func() {
+			desired, err := buildBucketPolicy("reports-bucket", &computev1.BucketPolicy{
+				Statements: []computev1.PolicyStatement{
+					{
+						Principals: []string{"*"},
+						Actions:    []string{"s3:GetObject"},
+						Conditions: map[string]map[string][]string{"StringEquals": {"aws:PrincipalOrgID": {"o-123"}}},
+					},
+					{
+						Sid:              "Writers",
+						Principals:       []string{"123456789012", "arn:aws:iam::210987654321:role/writer"},
+						Actions:          []string{"s3:PutObject", "s3:DeleteObject"},
+						ResourcePrefixes: []string{"uploads/"},
+					},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			// What GetBucketPolicy returns for it: keys reordered, single values unwrapped,
+			// "*" spelled out and the account ID expanded
+			fromS3 := `{"Version":"2012-10-17","Statement":[` +
+				`{"Principal":{"AWS":"*"},"Effect":"Allow","Action":"s3:GetObject",` +
+				`"Resource":["arn:aws:s3:::reports-bucket","arn:aws:s3:::reports-bucket/*"],` +
+				`"Condition":{"StringEquals":{"aws:PrincipalOrgID":"o-123"}}},` +
+				`{"Sid":"Writers","Effect":"Allow",` +
+				`"Principal":{"AWS":["arn:aws:iam::210987654321:role/writer","arn:aws:iam::123456789012:root"]},` +
+				`"Action":["s3:DeleteObject","s3:PutObject"],"Resource":"arn:aws:s3:::reports-bucket/uploads/*"}]}`
+			Expect(policiesEqual(fromS3, desired)).To(BeTrue())
+
+			changed := strings.Replace(fromS3, `"Action":"s3:GetObject"`, `"Action":"s3:*"`, 1)
+			Expect(policiesEqual(changed, desired)).To(BeFalse())
+		})
+
+		It("should reject raw JSON before calling AWS", func() {
+			_, err := buildBucketPolicy("reports-bucket", &computev1.BucketPolicy{JSON: `{"Statement": [`})
+			Expect(isInvalidSpec(err)).To(BeTrue())
+		})
+	})
+
+	Context("When emptying a bucket", func() {
+		It("should delete both object versions and delete markers", func() {
+			objects := objectIdentifiers(&s3.ListObjectVersionsOutput{
+				Versions: []s3types.ObjectVersion{
+					{Key: aws.String("a.txt"), VersionId: aws.String("v1")},
+					{Key: aws.String("a.txt"), VersionId: aws.String("v2")},
+				},
+				DeleteMarkers: []s3types.DeleteMarkerEntry{
+					{Key: aws.String("b.txt"), VersionId: aws.String("v3")},
+				},
+			})
+			Expect(objects).To(HaveLen(3))
+			Expect(*objects[2].Key).To(Equal("b.txt"))
+			Expect(*objects[2].VersionId).To(Equal("v3"))
+		})
+	})
+
+	Context("When syncing bucket tags", func() {
+		It("should let spec tags override labels but never the ownership tags", func() {
+			s3bucket := &computev1.S3Bucket{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "reports",
+					Namespace: "team-a",
+					UID:       "1234",
+					Labels:    map[string]string{"team": "data", "cost-center": "42"},
+				},
+			}
+
+			tags := resourceTags(s3bucket, map[string]string{"team": "platform", "env": "prod"},
+				map[string]string{"cost-center": "7", managedByTagKey: "someone-else"})
+			Expect(tags).To(HaveKeyWithValue("env", "prod"))
+			Expect(tags).To(HaveKeyWithValue("team", "data"))
+			Expect(tags).To(HaveKeyWithValue("cost-center", "7"))
+			Expect(tags).To(HaveKeyWithValue(nameTagKey, "reports"))
+			Expect(tags).To(HaveKeyWithValue(managedByTagKey, managedByTagValue))
+			Expect(tags).To(HaveKeyWithValue(ownerUIDTagKey, "1234"))
+		})
+
+		It("should only remove tags the operator managed before", func() {
+			merged := mergeBucketTags(
+				map[string]string{"external": "keep", "old": "x", "team": "data"},
+				map[string]string{"old": "x", "team": "data"},
+				map[string]string{"team": "analytics"},
+			)
+			Expect(merged).To(Equal(map[string]string{"external": "keep", "team": "analytics"}))
+		})
+	})
+
+	Context("When scheduling drift checks", func() {
+		It("should wait for the resync interval unless the spec changed", func() {
+			// LastSyncTime only has second precision
+			now := time.Now().Truncate(time.Second)
+			s3bucket := &computev1.S3Bucket{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status: computev1.S3BucketStatus{
+					ObservedGeneration: 2,
+					LastSyncTime:       now.Add(-time.Minute).Format(time.RFC3339),
+				},
+			}
+			Expect(bucketResyncWait(s3bucket, now)).To(Equal(s3ResyncInterval - time.Minute))
+
+			s3bucket.Status.LastSyncTime = now.Add(-2 * s3ResyncInterval).Format(time.RFC3339)
+			Expect(bucketResyncWait(s3bucket, now)).To(BeZero())
+
+			s3bucket.Status.LastSyncTime = now.Format(time.RFC3339)
+			s3bucket.Generation = 3
+			Expect(bucketResyncWait(s3bucket, now)).To(BeZero())
+		})
+	})
+
+	Context("When building CORS rules", func() {
+		It("should only set the optional fields that are given", func() {
+			rules := buildCORSRules([]computev1.CORSRule{
+				{
+					AllowedOrigins: []string{"https://app.example.com"},
+					AllowedMethods: []string{"PUT", "POST"},
+					AllowedHeaders: []string{"*"},
+					MaxAgeSeconds:  3000,
+				},
+				{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}},
+			})
+			Expect(rules).To(HaveLen(2))
+			Expect(rules[0].AllowedMethods).To(Equal([]string{"PUT", "POST"}))
+			Expect(*rules[0].MaxAgeSeconds).To(Equal(int32(3000)))
+			Expect(rules[1].ID).To(BeNil())
+			Expect(rules[1].MaxAgeSeconds).To(BeNil())
+		})
+	})
+
+	Context("When configuring website hosting", func() {
+		It("should derive the website endpoint from the region", func() {
+			Expect(websiteEndpoint("docs", "us-east-1")).To(Equal("http://docs.s3-website-us-east-1.amazonaws.com"))
+			Expect(websiteEndpoint("docs", "ap-south-1")).To(Equal("http://docs.s3-website.ap-south-1.amazonaws.com"))
+			Expect(websiteEndpoint("docs", "cn-north-1")).To(Equal("http://docs.s3-website.cn-north-1.amazonaws.com.cn"))
+		})
+
+		It("should leave out index and error documents when redirecting everything", func() {
+			config := buildWebsiteConfiguration(&computev1.WebsiteConfig{
+				RedirectAllRequestsTo: &computev1.WebsiteRedirect{HostName: "docs.example.com", Protocol: "https"},
+			})
+			Expect(config.IndexDocument).To(BeNil())
+			Expect(config.ErrorDocument).To(BeNil())
+			Expect(*config.RedirectAllRequestsTo.HostName).To(Equal("docs.example.com"))
+		})
+	})
 })
@@ -92,6 +92,17 @@ var _ = AfterSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 })
 
+// findCondition returns the condition of type condType, or an empty one if it is not set.
+// Specs running against the fake AWS backend use it to follow the resource's conditions.
+func findCondition(conditions []computev1.Condition, condType string) computev1.Condition {
+	for _, condition := range conditions {
+		if condition.Type == condType {
+			return condition
+		}
+	}
+	return computev1.Condition{}
+}
+
 // getFirstFoundEnvTestBinaryDir locates the first binary in the specified path.
 // ENVTEST-based tests depend on specific binaries, usually located in paths set by
 // controller-runtime. When running tests directly (e.g., via an IDE) without using
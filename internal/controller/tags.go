@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// managedByTagKey/managedByTagValue mark AWS resources created or adopted by the operator
+	managedByTagKey   = "ManagedBy"
+	managedByTagValue = "ec2instance-operator"
+	// namespaceTagKey carries the namespace of the owning custom resource
+	namespaceTagKey = "Namespace"
+	// ownerUIDTagKey carries the UID of the custom resource that owns an AWS resource
+	ownerUIDTagKey = "OwnerUID"
+	// nameTagKey carries the name of the owning custom resource
+	nameTagKey = "Name"
+
+	// maxTagKeyLength is the longest tag key AWS accepts
+	maxTagKeyLength = 128
+	// reservedTagPrefix marks tag keys only AWS may set
+	reservedTagPrefix = "aws:"
+)
+
+// ownershipTags returns the tags that tie an AWS resource to the custom resource managing it
+func ownershipTags(obj metav1.Object) map[string]string {
+	return map[string]string{
+		managedByTagKey: managedByTagValue,
+		namespaceTagKey: obj.GetNamespace(),
+		ownerUIDTagKey:  string(obj.GetUID()),
+	}
+}
+
+// resourceTags returns the full tag set for an AWS resource: the default tags of its
+// ProviderConfig, overridden by the labels of the custom resource, overridden by the tags from
+// its spec, overridden by the Name and ownership tags. Labels that are not valid tag keys are
+// skipped.
+func resourceTags(obj metav1.Object, defaultTags, specTags map[string]string) map[string]string {
+	tags := map[string]string{}
+	for key, value := range defaultTags {
+		tags[key] = value
+	}
+	for key, value := range obj.GetLabels() {
+		if len(key) > maxTagKeyLength || strings.HasPrefix(key, reservedTagPrefix) {
+			continue
+		}
+		tags[key] = value
+	}
+	for key, value := range specTags {
+		tags[key] = value
+	}
+	tags[nameTagKey] = obj.GetName()
+	for key, value := range ownershipTags(obj) {
+		tags[key] = value
+	}
+	return tags
+}
+
+// sortedTagKeys returns the keys of tags in a stable order, so requests built from a map
+// do not change from one reconcile to the next
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}